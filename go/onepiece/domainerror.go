@@ -0,0 +1,169 @@
+package onepiece
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// ErrorStatus is the transport independent category of a DomainError, mapped to HTTP, NATS and gRPC status codes.
+type ErrorStatus string
+
+const (
+	StatusInvalidArgument    ErrorStatus = "invalid_argument"
+	StatusNotFound           ErrorStatus = "not_found"
+	StatusAlreadyExists      ErrorStatus = "already_exists"
+	StatusFailedPrecondition ErrorStatus = "failed_precondition"
+	StatusAborted            ErrorStatus = "aborted"
+	StatusUnknown            ErrorStatus = "unknown"
+)
+
+// HTTPStatus returns the HTTP status code matching the ErrorStatus.
+func (s ErrorStatus) HTTPStatus() int {
+	switch s {
+	case StatusInvalidArgument:
+		return http.StatusBadRequest
+	case StatusNotFound:
+		return http.StatusNotFound
+	case StatusAlreadyExists, StatusAborted:
+		return http.StatusConflict
+	case StatusFailedPrecondition:
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// DomainError is an error expected by the domain, identified by a stable code clients can rely on. Two domain errors
+// with the same code match with errors.Is, even after travelling through a transport.
+type DomainError struct {
+	Code    string            `json:"code"`
+	Status  ErrorStatus       `json:"status"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+
+	err error
+}
+
+func NewDomainError(code string, status ErrorStatus, message string) *DomainError {
+	return &DomainError{
+		Code:    code,
+		Status:  status,
+		Message: message,
+	}
+}
+
+func (e *DomainError) Error() string {
+	return e.Message
+}
+
+func (e *DomainError) Is(target error) bool {
+	t, ok := target.(*DomainError)
+	return ok && t.Code == e.Code
+}
+
+// Unwrap returns the registered sentinel error the DomainError stands for, if any.
+func (e *DomainError) Unwrap() error {
+	return e.err
+}
+
+// WithDetails returns a copy of the error carrying the given details.
+func (e *DomainError) WithDetails(details map[string]string) *DomainError {
+	clone := *e
+	clone.Details = details
+	return &clone
+}
+
+type registeredError struct {
+	err    error
+	code   string
+	status ErrorStatus
+}
+
+// ErrorRegistry assigns codes to sentinel errors, so they can be sent to clients as a DomainError and reconstructed on
+// the other side.
+type ErrorRegistry struct {
+	errors []registeredError
+	byCode map[string]registeredError
+	mu     sync.RWMutex
+}
+
+func NewErrorRegistry() *ErrorRegistry {
+	return &ErrorRegistry{
+		byCode: make(map[string]registeredError),
+	}
+}
+
+// Register assigns the code and status to the sentinel error. It panics if the code is already registered, since
+// Resolve could not tell the two errors apart.
+func (r *ErrorRegistry) Register(err error, code string, status ErrorStatus) *ErrorRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.byCode[code]; ok {
+		panic(fmt.Sprintf("onepiece: error code %q registered twice", code))
+	}
+	entry := registeredError{err: err, code: code, status: status}
+	r.errors = append(r.errors, entry)
+	r.byCode[code] = entry
+	return r
+}
+
+// AsDomainError returns err as a DomainError, either because it already is one or because it wraps a registered
+// sentinel error.
+func (r *ErrorRegistry) AsDomainError(err error) (*DomainError, bool) {
+	var domainErr *DomainError
+	if errors.As(err, &domainErr) {
+		return domainErr, true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, entry := range r.errors {
+		if errors.Is(err, entry.err) {
+			return &DomainError{
+				Code:    entry.code,
+				Status:  entry.status,
+				Message: entry.err.Error(),
+				err:     entry.err,
+			}, true
+		}
+	}
+
+	return nil, false
+}
+
+// Resolve links a DomainError received from a transport to the sentinel error registered with its code, so
+// errors.Is matches that sentinel error.
+func (r *ErrorRegistry) Resolve(err *DomainError) *DomainError {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	resolved := *err
+	if entry, ok := r.byCode[err.Code]; ok {
+		resolved.err = entry.err
+		if resolved.Status == "" {
+			resolved.Status = entry.status
+		}
+	}
+	if resolved.Status == "" {
+		resolved.Status = StatusUnknown
+	}
+
+	return &resolved
+}
+
+var DefaultErrorRegistry = NewErrorRegistry().
+	Register(ErrTerminalState, "terminal_state", StatusFailedPrecondition)
+
+func RegisterError(err error, code string, status ErrorStatus) *ErrorRegistry {
+	return DefaultErrorRegistry.Register(err, code, status)
+}
+
+func AsDomainError(err error) (*DomainError, bool) {
+	return DefaultErrorRegistry.AsDomainError(err)
+}
+
+func ResolveDomainError(err *DomainError) *DomainError {
+	return DefaultErrorRegistry.Resolve(err)
+}
@@ -0,0 +1,85 @@
+package onepiece_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+var errPlanExists = onepiece.NewDomainError("plan_exists", onepiece.StatusAlreadyExists, "plan already exists")
+var errPlanNotFound = errors.New("plan not found")
+
+func TestDomainError(t *testing.T) {
+	t.Run("matches errors with the same code", func(t *testing.T) {
+		received := &onepiece.DomainError{Code: "plan_exists", Message: "plan d83a3744 already exists"}
+
+		require.ErrorIs(t, received, errPlanExists)
+		require.ErrorIs(t, fmt.Errorf("create plan: %w", errPlanExists), received)
+		require.NotErrorIs(t, received, onepiece.NewDomainError("plan_not_found", onepiece.StatusNotFound, "plan not found"))
+	})
+
+	t.Run("adds details without changing the sentinel error", func(t *testing.T) {
+		detailed := errPlanExists.WithDetails(map[string]string{"planId": "d83a3744"})
+
+		require.ErrorIs(t, detailed, errPlanExists)
+		require.Equal(t, map[string]string{"planId": "d83a3744"}, detailed.Details)
+		require.Nil(t, errPlanExists.Details)
+	})
+}
+
+func TestErrorRegistry(t *testing.T) {
+	registry := onepiece.NewErrorRegistry().
+		Register(errPlanNotFound, "plan_not_found", onepiece.StatusNotFound)
+
+	t.Run("converts registered sentinel errors", func(t *testing.T) {
+		domainErr, ok := registry.AsDomainError(fmt.Errorf("archive plan: %w", errPlanNotFound))
+
+		require.True(t, ok)
+		require.Equal(t, "plan_not_found", domainErr.Code)
+		require.Equal(t, onepiece.StatusNotFound, domainErr.Status)
+		require.Equal(t, "plan not found", domainErr.Message)
+		require.Equal(t, http.StatusNotFound, domainErr.Status.HTTPStatus())
+	})
+
+	t.Run("keeps domain errors as they are", func(t *testing.T) {
+		domainErr, ok := registry.AsDomainError(fmt.Errorf("create plan: %w", errPlanExists))
+
+		require.True(t, ok)
+		require.Same(t, errPlanExists, domainErr)
+	})
+
+	t.Run("ignores unknown errors", func(t *testing.T) {
+		_, ok := registry.AsDomainError(errors.New("connection refused"))
+		require.False(t, ok)
+	})
+
+	t.Run("resolves received errors to registered sentinel errors", func(t *testing.T) {
+		resolved := registry.Resolve(&onepiece.DomainError{Code: "plan_not_found", Message: "plan not found"})
+
+		require.ErrorIs(t, resolved, errPlanNotFound)
+		require.Equal(t, onepiece.StatusNotFound, resolved.Status)
+	})
+
+	t.Run("resolves unknown codes to an unknown status", func(t *testing.T) {
+		resolved := registry.Resolve(&onepiece.DomainError{Code: "plan_locked", Message: "plan locked"})
+
+		require.Equal(t, onepiece.StatusUnknown, resolved.Status)
+		require.Equal(t, http.StatusInternalServerError, resolved.Status.HTTPStatus())
+	})
+
+	t.Run("rejects a code registered twice", func(t *testing.T) {
+		require.PanicsWithValue(t, `onepiece: error code "plan_not_found" registered twice`, func() {
+			registry.Register(errors.New("plan missing"), "plan_not_found", onepiece.StatusNotFound)
+		})
+	})
+
+	t.Run("registers the terminal state error by default", func(t *testing.T) {
+		domainErr, ok := onepiece.AsDomainError(onepiece.ErrTerminalState)
+
+		require.True(t, ok)
+		require.Equal(t, "terminal_state", domainErr.Code)
+	})
+}
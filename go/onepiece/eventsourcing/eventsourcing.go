@@ -8,7 +8,12 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/straw-hat-team/onepiece/go/onepiece"
 	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecemessage"
-	"io"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"time"
 )
 
 type Result[Event any] struct {
@@ -25,8 +30,18 @@ const (
 
 var (
 	ErrOptimisticConcurrency = errors.New("optimistic concurrency error")
+	// ErrInvalidCommand is the DomainError of a command the transports cannot decode.
+	ErrInvalidCommand = onepiece.NewDomainError("invalid_command", onepiece.StatusInvalidArgument, "invalid command")
 )
 
+// ErrorCodeOptimisticConcurrency is the DomainError code clients receive when the expected revision of a command no
+// longer matches the stream, so they can reload the stream and retry.
+const ErrorCodeOptimisticConcurrency = "optimistic_concurrency"
+
+func init() {
+	onepiece.RegisterError(ErrOptimisticConcurrency, ErrorCodeOptimisticConcurrency, onepiece.StatusAborted)
+}
+
 type Any = esdb.Any
 type StreamExists = esdb.StreamExists
 type NoStream = esdb.NoStream
@@ -36,9 +51,9 @@ type ExpectedRevision = esdb.ExpectedRevision
 type CorrelationId string
 type CausationId string
 
-// CommandHandler is a function that receives a command and returns a list of events.
-// TODO: figure out how to avoid passing the db here. Take into consideration multi-tenancy.
-type CommandHandler[Command any, Event any] func(context context.Context, db *esdb.Client, command Command, opts *Options) (*Result[Event], error)
+// CommandHandler is a function that receives a command and returns a list of events. The EventStore is passed on every
+// call, so a handler can serve several tenants.
+type CommandHandler[Command any, Event any] func(context context.Context, store EventStore, command Command, opts *Options) (*Result[Event], error)
 
 type StreamId[Command any] func(command Command) (string, error)
 
@@ -51,6 +66,45 @@ type Options struct {
 	CausationId      *CausationId
 }
 
+// DeciderOption configures the CommandHandler returned by NewDecider.
+type DeciderOption func(config *deciderConfig)
+
+type deciderConfig struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	replayHook ReplayHook
+	logger     *slog.Logger
+}
+
+func newDeciderConfig(options []DeciderOption) *deciderConfig {
+	config := &deciderConfig{
+		tracer:     otel.GetTracerProvider().Tracer(instrumentationName),
+		propagator: otel.GetTextMapPropagator(),
+		replayHook: func(context.Context, Replay) {},
+		logger:     slog.Default(),
+	}
+	for _, option := range options {
+		option(config)
+	}
+	return config
+}
+
+// Replay describes how the state was rebuilt from the stream before deciding a command.
+type Replay struct {
+	StreamId   string
+	EventCount int
+	Duration   time.Duration
+}
+
+type ReplayHook func(ctx context.Context, replay Replay)
+
+// WithReplayHook sets a function called every time the state is rebuilt from the stream.
+func WithReplayHook(hook ReplayHook) DeciderOption {
+	return func(config *deciderConfig) {
+		config.replayHook = hook
+	}
+}
+
 type UnmarshalEvent[Event any] func(eventType string, data []byte) (Event, error)
 type MarshalEvent[Event any] func(event Event) (ContentType, []byte, error)
 
@@ -60,101 +114,69 @@ var (
 	maxReadSize = ^uint64(0)
 )
 
+// NewDecider returns a CommandHandler that rebuilds the state of the command stream, decides the command and appends
+// the resulting events. Errors returned by Decide are returned as they are, for an ErrorClassifier to classify.
 func NewDecider[State any, Command any, Event any](
 	decider *onepiece.Decider[State, Command, Event],
 	getStreamId StreamId[Command],
 	marshalEvent MarshalEvent[Event],
 	unmarshalEvent UnmarshalEvent[Event],
 	getEventType GetEventType[Event],
+	options ...DeciderOption,
 ) CommandHandler[Command, Event] {
-	return func(context context.Context, db *esdb.Client, command Command, opts *Options) (*Result[Event], error) {
+	config := newDeciderConfig(options)
+
+	return func(context context.Context, store EventStore, command Command, opts *Options) (result *Result[Event], err error) {
+		context, span := config.tracer.Start(context, "onepiece.Dispatch", trace.WithSpanKind(trace.SpanKindInternal))
+		defer func() { endSpan(span, err) }()
+
+		correlationId := getCorrelation(opts)
+		causationId := getCausationId(opts)
+		logger := config.logger.With(
+			slog.String(LogKeyCommandType, MessageTypeOf(command)),
+			slog.String(LogKeyCorrelationId, string(*correlationId)),
+			slog.String(LogKeyCausationId, string(*causationId)),
+		)
+		defer func() { logResult(context, logger, result, err) }()
+
 		streamID, err := getStreamId(command)
 		if err != nil {
 			return nil, err
 		}
-		stream, err := db.ReadStream(context, streamID, esdb.ReadStreamOptions{
-			Direction: esdb.Forwards,
-			From:      esdb.Start{},
-		}, maxReadSize)
+		span.SetAttributes(attribute.String(attributeStreamId, streamID))
+		logger = logger.With(slog.String(LogKeyStreamId, streamID))
 
+		replayStart := time.Now()
+		previousEvents, lastRevision, err := readStream(context, config, store, streamID, unmarshalEvent)
 		if err != nil {
 			return nil, err
 		}
 
-		defer stream.Close()
-
-		state := decider.InitialState()
-
-		var lastResolvedEvent *esdb.ResolvedEvent
-
-		for {
-			resolvedEvent, err := stream.Recv()
-
-			if err, ok := esdb.FromError(err); !ok {
-				if err.Code() == esdb.ErrorCodeResourceNotFound {
-					break
-				} else if errors.Is(err, io.EOF) {
-					break
-				} else {
-					return nil, err
-				}
-			}
-
-			event, err := unmarshalEvent(
-				resolvedEvent.Event.EventType,
-				resolvedEvent.Event.Data,
-			)
-			if err != nil {
-				return nil, err
-			}
-
-			state = decider.Evolve(state, event)
-			lastResolvedEvent = resolvedEvent
+		state := evolveState(context, config, decider, previousEvents)
+		replay := Replay{
+			StreamId:   streamID,
+			EventCount: len(previousEvents),
+			Duration:   time.Since(replayStart),
 		}
+		config.replayHook(context, replay)
+		logReplay(context, logger, replay, lastRevision)
 
 		if decider.IsTerminal(state) {
 			return nil, onepiece.ErrTerminalState
 		}
 
-		events, err := decider.Decide(state, command)
-
+		events, err := decideEvents(context, config, decider, state, command)
 		if err != nil {
 			return nil, err
 		}
 
-		metadata, err := getEventMetadata(opts)
+		eventData, err := marshalEvents(context, config, events, opts, correlationId, causationId, marshalEvent, getEventType)
 		if err != nil {
 			return nil, err
 		}
 
-		eventData := make([]esdb.EventData, len(events))
-		for i, event := range events {
-			eventType, err := getEventType(event)
-			if err != nil {
-				return nil, err
-			}
-
-			contentType, data, err := marshalEvent(event)
-			if err != nil {
-				return nil, err
-			}
-
-			eventData[i] = esdb.EventData{
-				EventType:   eventType.String(),
-				ContentType: contentType,
-				Data:        data,
-				Metadata:    metadata,
-			}
-		}
-
-		writeResult, err := db.AppendToStream(context, streamID, esdb.AppendToStreamOptions{
-			ExpectedRevision: getExpectedRevision(opts, lastResolvedEvent),
-		}, eventData...)
-
-		if err, ok := esdb.FromError(err); !ok {
-			if err.Code() == esdb.ErrorCodeWrongExpectedVersion {
-				return nil, ErrOptimisticConcurrency
-			}
+		writeResult, err := appendToStream(context, config, store, streamID, getExpectedRevision(opts, lastRevision), eventData)
+		if err != nil {
 			return nil, err
 		}
 
@@ -165,27 +187,176 @@ func NewDecider[State any, Command any, Event any](
 	}
 }
 
-func getExpectedRevision(opts *Options, lastResolvedEvent *esdb.ResolvedEvent) ExpectedRevision {
+// readStream returns the events of the stream together with the revision of the last one, nil when the stream is
+// empty.
+func readStream[Event any](
+	ctx context.Context,
+	config *deciderConfig,
+	store EventStore,
+	streamID string,
+	unmarshalEvent UnmarshalEvent[Event],
+) (_ []Event, _ *uint64, err error) {
+	ctx, span := config.tracer.Start(ctx, "onepiece.ReadStream", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { endSpan(span, err) }()
+	span.SetAttributes(attribute.String(attributeStreamId, streamID))
+
+	recordedEvents, err := store.ReadStream(ctx, streamID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var events []Event
+	var lastRevision *uint64
+
+	for _, recordedEvent := range recordedEvents {
+		event, err := unmarshalEvent(recordedEvent.EventType, recordedEvent.Data)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		events = append(events, event)
+		revision := recordedEvent.Revision
+		lastRevision = &revision
+	}
+
+	span.SetAttributes(attribute.Int(attributeEventCount, len(events)))
+
+	return events, lastRevision, nil
+}
+
+func evolveState[State any, Command any, Event any](
+	ctx context.Context,
+	config *deciderConfig,
+	decider *onepiece.Decider[State, Command, Event],
+	events []Event,
+) State {
+	_, span := config.tracer.Start(ctx, "onepiece.Evolve")
+	defer span.End()
+	span.SetAttributes(attribute.Int(attributeEventCount, len(events)))
+
+	state := decider.InitialState()
+	for _, event := range events {
+		state = decider.Evolve(state, event)
+	}
+
+	return state
+}
+
+func decideEvents[State any, Command any, Event any](
+	ctx context.Context,
+	config *deciderConfig,
+	decider *onepiece.Decider[State, Command, Event],
+	state State,
+	command Command,
+) (_ []Event, err error) {
+	_, span := config.tracer.Start(ctx, "onepiece.Decide")
+	defer func() { endSpan(span, err) }()
+
+	events, err := decider.Decide(state, command)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int(attributeEventCount, len(events)))
+
+	return events, nil
+}
+
+func marshalEvents[Event any](
+	ctx context.Context,
+	config *deciderConfig,
+	events []Event,
+	opts *Options,
+	correlationId *CorrelationId,
+	causationId *CausationId,
+	marshalEvent MarshalEvent[Event],
+	getEventType GetEventType[Event],
+) (_ []EventData, err error) {
+	ctx, span := config.tracer.Start(ctx, "onepiece.Marshal")
+	defer func() { endSpan(span, err) }()
+	span.SetAttributes(attribute.Int(attributeEventCount, len(events)))
+
+	metadata, err := getEventMetadata(ctx, config, opts, correlationId, causationId)
+	if err != nil {
+		return nil, err
+	}
+
+	eventData := make([]EventData, len(events))
+	for i, event := range events {
+		eventType, err := getEventType(event)
+		if err != nil {
+			return nil, err
+		}
+
+		contentType, data, err := marshalEvent(event)
+		if err != nil {
+			return nil, err
+		}
+
+		eventData[i] = EventData{
+			EventID:     uuid.Must(uuid.NewV4()),
+			EventType:   eventType.String(),
+			ContentType: contentType,
+			Data:        data,
+			Metadata:    metadata,
+		}
+	}
+
+	return eventData, nil
+}
+
+func appendToStream(
+	ctx context.Context,
+	config *deciderConfig,
+	store EventStore,
+	streamID string,
+	expectedRevision ExpectedRevision,
+	eventData []EventData,
+) (_ *WriteResult, err error) {
+	ctx, span := config.tracer.Start(ctx, "onepiece.AppendToStream", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { endSpan(span, err) }()
+	span.SetAttributes(
+		attribute.String(attributeStreamId, streamID),
+		attribute.Int(attributeEventCount, len(eventData)),
+	)
+
+	writeResult, err := store.AppendToStream(ctx, streamID, expectedRevision, eventData)
+	if err != nil {
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int64(attributeNextExpectedVersion, int64(writeResult.NextExpectedVersion)))
+
+	return writeResult, nil
+}
+
+func getExpectedRevision(opts *Options, lastRevision *uint64) ExpectedRevision {
 	if opts != nil && opts.ExpectedRevision != nil {
 		return opts.ExpectedRevision
-	} else if lastResolvedEvent == nil {
+	} else if lastRevision == nil {
 		return NoStream{}
 	} else {
-		return Revision(lastResolvedEvent.OriginalEvent().EventNumber)
+		return Revision(*lastRevision)
 	}
 }
 
-func getEventMetadata(opts *Options) ([]byte, error) {
-	var metadata map[string]any
-
-	if opts != nil && opts.Metadata != nil {
-		metadata = opts.Metadata
-	} else {
-		metadata = make(map[string]any)
+func getEventMetadata(
+	ctx context.Context,
+	config *deciderConfig,
+	opts *Options,
+	correlationId *CorrelationId,
+	causationId *CausationId,
+) ([]byte, error) {
+	metadata := make(Metadata)
+
+	if opts != nil {
+		for key, value := range opts.Metadata {
+			metadata[key] = value
+		}
 	}
 
-	metadata["$correlationId"] = getCorrelation(opts)
-	metadata["$causationId"] = getCausationId(opts)
+	metadata["$correlationId"] = correlationId
+	metadata["$causationId"] = causationId
+	config.propagator.Inject(ctx, MetadataCarrier(metadata))
 
 	bytes, err := json.Marshal(metadata)
 	if err != nil {
@@ -0,0 +1,95 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"github.com/EventStore/EventStore-Client-Go/v3/esdb"
+	"io"
+)
+
+type EventData = esdb.EventData
+type WriteResult = esdb.WriteResult
+
+// RecordedEvent is an event read from the stream of an EventStore.
+type RecordedEvent struct {
+	EventId   string
+	EventType string
+	Data      []byte
+	Metadata  []byte
+	// Revision is the position of the event in its stream, starting at 0.
+	Revision uint64
+}
+
+// EventStore reads and appends the events of the streams handled by a CommandHandler.
+type EventStore interface {
+	// ReadStream returns every event of the stream in order. A stream that does not exist has no events.
+	ReadStream(ctx context.Context, streamId string) ([]RecordedEvent, error)
+	// AppendToStream appends the events to the stream, failing with ErrOptimisticConcurrency when the stream does not
+	// match the expected revision.
+	AppendToStream(ctx context.Context, streamId string, expectedRevision ExpectedRevision, events []EventData) (*WriteResult, error)
+}
+
+// EventStoreDB is the EventStore backed by EventStoreDB.
+type EventStoreDB struct {
+	client *esdb.Client
+}
+
+func NewEventStoreDB(client *esdb.Client) *EventStoreDB {
+	return &EventStoreDB{client: client}
+}
+
+func (s *EventStoreDB) ReadStream(ctx context.Context, streamId string) ([]RecordedEvent, error) {
+	stream, err := s.client.ReadStream(ctx, streamId, esdb.ReadStreamOptions{
+		Direction: esdb.Forwards,
+		From:      esdb.Start{},
+	}, maxReadSize)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var events []RecordedEvent
+	for {
+		resolvedEvent, err := stream.Recv()
+
+		if err, ok := esdb.FromError(err); !ok {
+			if err.Code() == esdb.ErrorCodeResourceNotFound {
+				break
+			} else if errors.Is(err, io.EOF) {
+				break
+			} else {
+				return nil, err
+			}
+		}
+
+		events = append(events, RecordedEvent{
+			EventId:   resolvedEvent.Event.EventID.String(),
+			EventType: resolvedEvent.Event.EventType,
+			Data:      resolvedEvent.Event.Data,
+			Metadata:  resolvedEvent.Event.UserMetadata,
+			Revision:  resolvedEvent.OriginalEvent().EventNumber,
+		})
+	}
+
+	return events, nil
+}
+
+func (s *EventStoreDB) AppendToStream(
+	ctx context.Context,
+	streamId string,
+	expectedRevision ExpectedRevision,
+	events []EventData,
+) (*WriteResult, error) {
+	writeResult, err := s.client.AppendToStream(ctx, streamId, esdb.AppendToStreamOptions{
+		ExpectedRevision: expectedRevision,
+	}, events...)
+
+	if err, ok := esdb.FromError(err); !ok {
+		if err.Code() == esdb.ErrorCodeWrongExpectedVersion {
+			return nil, ErrOptimisticConcurrency
+		}
+		return nil, err
+	}
+
+	return writeResult, nil
+}
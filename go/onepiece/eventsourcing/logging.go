@@ -0,0 +1,82 @@
+package eventsourcing
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"log/slog"
+)
+
+// Keys of the attributes attached to every log record, shared with the transports so records can be joined with
+// the events they produced.
+const (
+	LogKeyStreamId      = "stream_id"
+	LogKeyCommandType   = "command_type"
+	LogKeyCorrelationId = "correlation_id"
+	LogKeyCausationId   = "causation_id"
+	LogKeyRevision      = "revision"
+	LogKeyEventCount    = "event_count"
+	LogKeyErrorKind     = "error_kind"
+)
+
+// WithLogger sets the logger used to report every dispatched command. Defaults to slog.Default.
+func WithLogger(logger *slog.Logger) DeciderOption {
+	return func(config *deciderConfig) {
+		config.logger = logger
+	}
+}
+
+func logReplay(ctx context.Context, logger *slog.Logger, replay Replay, lastRevision *uint64) {
+	attrs := []slog.Attr{
+		slog.Int(LogKeyEventCount, replay.EventCount),
+		slog.Duration("duration", replay.Duration),
+	}
+	if lastRevision != nil {
+		attrs = append(attrs, slog.Uint64(LogKeyRevision, *lastRevision))
+	}
+
+	logger.LogAttrs(ctx, slog.LevelDebug, "stream replayed", attrs...)
+}
+
+func logResult[Event any](ctx context.Context, logger *slog.Logger, result *Result[Event], err error) {
+	if err == nil {
+		logger.LogAttrs(ctx, slog.LevelDebug, "command handled",
+			slog.Uint64(LogKeyRevision, result.NextExpectedVersion),
+			slog.Int(LogKeyEventCount, len(result.Events)),
+		)
+		return
+	}
+
+	kind := ErrorKindOf(err)
+	level := slog.LevelError
+	switch kind {
+	case ErrorKindDomain, ErrorKindTerminal:
+		level = slog.LevelInfo
+	case ErrorKindConcurrency:
+		level = slog.LevelWarn
+	}
+
+	logger.LogAttrs(ctx, level, "command failed",
+		slog.String(LogKeyErrorKind, string(kind)),
+		slog.Any("error", err),
+	)
+}
+
+// MessageTypeOf names a command or an event: the variant set in a protobuf oneof wrapper, the message name of any
+// other protobuf message, or the Go type otherwise.
+func MessageTypeOf(message any) string {
+	msg, ok := message.(proto.Message)
+	if !ok {
+		return fmt.Sprintf("%T", message)
+	}
+
+	reflectMsg := msg.ProtoReflect()
+	oneofs := reflectMsg.Descriptor().Oneofs()
+	if oneofs.Len() == 1 {
+		if field := reflectMsg.WhichOneof(oneofs.Get(0)); field != nil && field.Message() != nil {
+			return string(field.Message().FullName())
+		}
+	}
+
+	return string(reflectMsg.Descriptor().FullName())
+}
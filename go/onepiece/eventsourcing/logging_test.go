@@ -0,0 +1,79 @@
+package eventsourcing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"testing"
+)
+
+// lastLogRecord decodes the last JSON record written to the buffer.
+func lastLogRecord(t *testing.T, buffer *bytes.Buffer) map[string]any {
+	t.Helper()
+	lines := bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n"))
+	record := map[string]any{}
+	require.NoError(t, json.Unmarshal(lines[len(lines)-1], &record))
+	return record
+}
+
+func TestNewDeciderLogging(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	store := onepiecetesting.NewMemoryEventStore()
+	handler := newCounterHandler(eventsourcing.WithLogger(logger))
+
+	correlationId := eventsourcing.CorrelationId("correlation-1")
+	causationId := eventsourcing.CausationId("causation-1")
+	opts := func(expectedRevision eventsourcing.ExpectedRevision) *eventsourcing.Options {
+		return &eventsourcing.Options{
+			ExpectedRevision: expectedRevision,
+			CorrelationId:    &correlationId,
+			CausationId:      &causationId,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		opts    *eventsourcing.Options
+		level   string
+		msg     string
+		kind    any
+	}{
+		{name: "success", command: "increment", opts: opts(eventsourcing.NoStream{}), level: "DEBUG", msg: "command handled"},
+		{name: "unclassified decide error", command: "fail", opts: opts(nil), level: "ERROR", msg: "command failed", kind: "infrastructure"},
+		{name: "optimistic concurrency", command: "increment", opts: opts(eventsourcing.NoStream{}), level: "WARN", msg: "command failed", kind: "concurrency"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer.Reset()
+			_, _ = handler(context.Background(), store, tt.command, tt.opts)
+
+			record := lastLogRecord(t, &buffer)
+			require.Equal(t, tt.level, record["level"])
+			require.Equal(t, tt.msg, record["msg"])
+			require.Equal(t, "counter-1", record[eventsourcing.LogKeyStreamId])
+			require.Equal(t, "string", record[eventsourcing.LogKeyCommandType])
+			require.Equal(t, "correlation-1", record[eventsourcing.LogKeyCorrelationId])
+			require.Equal(t, "causation-1", record[eventsourcing.LogKeyCausationId])
+			require.Equal(t, tt.kind, record[eventsourcing.LogKeyErrorKind])
+		})
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		buffer.Reset()
+		_, err := handler(context.Background(), store, "increment", opts(nil))
+		require.NoError(t, err)
+
+		replayed := map[string]any{}
+		lines := bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n"))
+		require.NoError(t, json.Unmarshal(lines[0], &replayed))
+		require.Equal(t, "stream replayed", replayed["msg"])
+		require.Equal(t, float64(2), replayed[eventsourcing.LogKeyEventCount])
+		require.Equal(t, float64(1), replayed[eventsourcing.LogKeyRevision])
+	})
+}
@@ -0,0 +1,169 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"runtime/debug"
+	"time"
+)
+
+var (
+	ErrCommandPanicked = errors.New("command handler panicked")
+)
+
+// Middleware decorates a CommandHandler with a cross-cutting concern.
+type Middleware[Command any, Event any] func(next CommandHandler[Command, Event]) CommandHandler[Command, Event]
+
+// Chain wraps the handler with the middlewares. The first middleware is the outermost one, so it sees the command
+// first and the result last.
+func Chain[Command any, Event any](
+	handler CommandHandler[Command, Event],
+	middlewares ...Middleware[Command, Event],
+) CommandHandler[Command, Event] {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// PanicError is the error of a recovered panic. Error only describes the panic value, so the stack never reaches
+// callers; log Stack instead.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrCommandPanicked, e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return ErrCommandPanicked
+}
+
+// Recover turns a panic raised while handling a command, for example inside Decide or Evolve, into a PanicError
+// wrapping ErrCommandPanicked.
+func Recover[Command any, Event any]() Middleware[Command, Event] {
+	return func(next CommandHandler[Command, Event]) CommandHandler[Command, Event] {
+		return func(ctx context.Context, store EventStore, command Command, opts *Options) (result *Result[Event], err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					result = nil
+					err = &PanicError{Value: r, Stack: debug.Stack()}
+				}
+			}()
+			return next(ctx, store, command, opts)
+		}
+	}
+}
+
+// Timeout bounds the time spent handling every command.
+func Timeout[Command any, Event any](timeout time.Duration) Middleware[Command, Event] {
+	return TimeoutFunc[Command, Event](func(_ Command) time.Duration {
+		return timeout
+	})
+}
+
+// TimeoutFunc bounds the time spent handling a command with a timeout chosen per command. A zero or negative
+// timeout leaves the context untouched.
+func TimeoutFunc[Command any, Event any](timeout func(command Command) time.Duration) Middleware[Command, Event] {
+	return func(next CommandHandler[Command, Event]) CommandHandler[Command, Event] {
+		return func(ctx context.Context, store EventStore, command Command, opts *Options) (*Result[Event], error) {
+			d := timeout(command)
+			if d <= 0 {
+				return next(ctx, store, command, opts)
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			return next(ctx, store, command, opts)
+		}
+	}
+}
+
+type ErrorKind string
+
+const (
+	ErrorKindDomain         ErrorKind = "domain"
+	ErrorKindTerminal       ErrorKind = "terminal"
+	ErrorKindConcurrency    ErrorKind = "concurrency"
+	ErrorKindInfrastructure ErrorKind = "infrastructure"
+)
+
+// ClassifiedError is an error returned by a CommandHandler together with its ErrorKind.
+type ClassifiedError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+type ErrorClassifier func(err error) ErrorKind
+
+// NewErrorClassifier returns an ErrorClassifier that treats any onepiece.DomainError, the sentinel errors registered in
+// onepiece.DefaultErrorRegistry and the given errors as domain errors. Terminal state and optimistic concurrency errors get their own kind, anything else is considered an
+// infrastructure error.
+func NewErrorClassifier(domainErrors ...error) ErrorClassifier {
+	return func(err error) ErrorKind {
+		switch {
+		case errors.Is(err, onepiece.ErrTerminalState):
+			return ErrorKindTerminal
+		case errors.Is(err, ErrOptimisticConcurrency):
+			return ErrorKindConcurrency
+		}
+
+		if _, ok := onepiece.AsDomainError(err); ok {
+			return ErrorKindDomain
+		}
+		for _, domainErr := range domainErrors {
+			if errors.Is(err, domainErr) {
+				return ErrorKindDomain
+			}
+		}
+
+		return ErrorKindInfrastructure
+	}
+}
+
+// ErrorKindOf returns the kind of a ClassifiedError, or classifies the error with the default ErrorClassifier
+// otherwise. A nil error has no kind.
+func ErrorKindOf(err error) ErrorKind {
+	if err == nil {
+		return ""
+	}
+
+	var classified *ClassifiedError
+	if errors.As(err, &classified) {
+		return classified.Kind
+	}
+
+	return NewErrorClassifier()(err)
+}
+
+// Classify wraps every error returned by the handler into a ClassifiedError.
+func Classify[Command any, Event any](classifier ErrorClassifier) Middleware[Command, Event] {
+	return func(next CommandHandler[Command, Event]) CommandHandler[Command, Event] {
+		return func(ctx context.Context, store EventStore, command Command, opts *Options) (*Result[Event], error) {
+			result, err := next(ctx, store, command, opts)
+			if err == nil {
+				return result, nil
+			}
+
+			var classified *ClassifiedError
+			if errors.As(err, &classified) {
+				return nil, err
+			}
+
+			return nil, &ClassifiedError{Kind: classifier(err), Err: err}
+		}
+	}
+}
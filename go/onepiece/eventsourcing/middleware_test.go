@@ -0,0 +1,124 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var errDomain = errors.New("domain error")
+
+var errRegistered = errors.New("registered error")
+
+func init() {
+	onepiece.RegisterError(errRegistered, "registered_error", onepiece.StatusFailedPrecondition)
+}
+
+type handler = eventsourcing.CommandHandler[string, string]
+type middleware = eventsourcing.Middleware[string, string]
+
+func returning(result *eventsourcing.Result[string], err error) handler {
+	return func(_ context.Context, _ eventsourcing.EventStore, _ string, _ *eventsourcing.Options) (*eventsourcing.Result[string], error) {
+		return result, err
+	}
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	trace := func(name string) middleware {
+		return func(next handler) handler {
+			return func(ctx context.Context, store eventsourcing.EventStore, command string, opts *eventsourcing.Options) (*eventsourcing.Result[string], error) {
+				calls = append(calls, name+" before")
+				result, err := next(ctx, store, command, opts)
+				calls = append(calls, name+" after")
+				return result, err
+			}
+		}
+	}
+
+	want := &eventsourcing.Result[string]{NextExpectedVersion: 1, Events: []string{"created"}}
+	result, err := eventsourcing.Chain(returning(want, nil), trace("outer"), trace("inner"))(context.Background(), nil, "create", nil)
+
+	require.NoError(t, err)
+	require.Equal(t, want, result)
+	require.Equal(t, []string{"outer before", "inner before", "inner after", "outer after"}, calls)
+}
+
+func TestRecover(t *testing.T) {
+	panicking := func(_ context.Context, _ eventsourcing.EventStore, _ string, _ *eventsourcing.Options) (*eventsourcing.Result[string], error) {
+		panic("evolve exploded")
+	}
+
+	result, err := eventsourcing.Chain(panicking, eventsourcing.Recover[string, string]())(context.Background(), nil, "create", nil)
+
+	require.Nil(t, result)
+	require.ErrorIs(t, err, eventsourcing.ErrCommandPanicked)
+	require.EqualError(t, err, "command handler panicked: evolve exploded")
+
+	var panicErr *eventsourcing.PanicError
+	require.ErrorAs(t, err, &panicErr)
+	require.Equal(t, "evolve exploded", panicErr.Value)
+	require.Contains(t, string(panicErr.Stack), "TestRecover")
+}
+
+func TestTimeoutFunc(t *testing.T) {
+	waiting := func(ctx context.Context, _ eventsourcing.EventStore, _ string, _ *eventsourcing.Options) (*eventsourcing.Result[string], error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	timeout := eventsourcing.TimeoutFunc[string, string](func(command string) time.Duration {
+		if command == "slow" {
+			return time.Millisecond
+		}
+		return 0
+	})
+
+	_, err := eventsourcing.Chain(waiting, timeout)(context.Background(), nil, "slow", nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var sawDeadline bool
+	inspecting := func(ctx context.Context, _ eventsourcing.EventStore, _ string, _ *eventsourcing.Options) (*eventsourcing.Result[string], error) {
+		_, sawDeadline = ctx.Deadline()
+		return nil, nil
+	}
+	_, err = eventsourcing.Chain(inspecting, timeout)(context.Background(), nil, "fast", nil)
+	require.NoError(t, err)
+	require.False(t, sawDeadline)
+}
+
+func TestClassify(t *testing.T) {
+	classify := eventsourcing.Classify[string, string](eventsourcing.NewErrorClassifier(errDomain))
+
+	tests := []struct {
+		name string
+		err  error
+		want eventsourcing.ErrorKind
+	}{
+		{name: "domain error", err: errDomain, want: eventsourcing.ErrorKindDomain},
+		{name: "registered error", err: errRegistered, want: eventsourcing.ErrorKindDomain},
+		{name: "terminal state", err: onepiece.ErrTerminalState, want: eventsourcing.ErrorKindTerminal},
+		{name: "optimistic concurrency", err: eventsourcing.ErrOptimisticConcurrency, want: eventsourcing.ErrorKindConcurrency},
+		{name: "infrastructure error", err: errors.New("connection refused"), want: eventsourcing.ErrorKindInfrastructure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := eventsourcing.Chain(returning(nil, tt.err), classify)(context.Background(), nil, "create", nil)
+
+			var classified *eventsourcing.ClassifiedError
+			require.ErrorAs(t, err, &classified)
+			require.Equal(t, tt.want, classified.Kind)
+			require.Equal(t, tt.want, eventsourcing.ErrorKindOf(err))
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	t.Run("success is not classified", func(t *testing.T) {
+		_, err := eventsourcing.Chain(returning(&eventsourcing.Result[string]{}, nil), classify)(context.Background(), nil, "create", nil)
+		require.NoError(t, err)
+		require.Equal(t, eventsourcing.ErrorKind(""), eventsourcing.ErrorKindOf(err))
+	})
+}
@@ -0,0 +1,69 @@
+package onepiecegrpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Dispatch calls the Dispatch method of a service registered with RegisterService, sending the options as metadata
+// next to the outgoing metadata of the context.
+// Rejections are returned as the errors decoded by FromStatus.
+func Dispatch[Command proto.Message](
+	ctx context.Context,
+	conn grpc.ClientConnInterface,
+	serviceName string,
+	command Command,
+	opts *eventsourcing.Options,
+	callOptions ...grpc.CallOption,
+) (*DispatchResult, error) {
+	md, err := optionsMetadata(opts)
+	if err != nil {
+		return nil, err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, MetadataCarrier(md))
+	if outgoing, ok := metadata.FromOutgoingContext(ctx); ok {
+		md = metadata.Join(outgoing, md)
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	var result DispatchResult
+	if err := conn.Invoke(ctx, DispatchMethod(serviceName), command, &result, callOptions...); err != nil {
+		if s, ok := status.FromError(err); ok {
+			return nil, FromStatus(s)
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+func optionsMetadata(opts *eventsourcing.Options) (metadata.MD, error) {
+	md := metadata.MD{}
+	if opts == nil {
+		return md, nil
+	}
+
+	if opts.ExpectedRevision != nil {
+		value, err := eventsourcing.FormatExpectedRevision(opts.ExpectedRevision)
+		if err != nil {
+			return nil, err
+		}
+		md.Set(MetadataExpectedRevision, value)
+	}
+	if opts.CorrelationId != nil {
+		md.Set(MetadataCorrelationId, string(*opts.CorrelationId))
+	}
+	if opts.CausationId != nil {
+		md.Set(MetadataCausationId, string(*opts.CausationId))
+	}
+	for name, value := range opts.Metadata {
+		md.Set(MetadataPrefix+name, fmt.Sprint(value))
+	}
+
+	return md, nil
+}
@@ -0,0 +1,169 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.32.0
+// 	protoc        (unknown)
+// source: strawhat/onepiece/eventsourcing/v1/dispatch.proto
+
+package onepiecegrpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// DispatchResult is the response of the Dispatch method of every aggregate served by onepiecegrpc.
+type DispatchResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	NextExpectedVersion uint64 `protobuf:"varint,1,opt,name=next_expected_version,json=nextExpectedVersion,proto3" json:"next_expected_version,omitempty"`
+	// Events appended to the stream by the command, packed from the Event oneof wrapper of the aggregate.
+	Events []*anypb.Any `protobuf:"bytes,2,rep,name=events,proto3" json:"events,omitempty"`
+}
+
+func (x *DispatchResult) Reset() {
+	*x = DispatchResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DispatchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DispatchResult) ProtoMessage() {}
+
+func (x *DispatchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DispatchResult.ProtoReflect.Descriptor instead.
+func (*DispatchResult) Descriptor() ([]byte, []int) {
+	return file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *DispatchResult) GetNextExpectedVersion() uint64 {
+	if x != nil {
+		return x.NextExpectedVersion
+	}
+	return 0
+}
+
+func (x *DispatchResult) GetEvents() []*anypb.Any {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+var File_strawhat_onepiece_eventsourcing_v1_dispatch_proto protoreflect.FileDescriptor
+
+var file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_rawDesc = []byte{
+	0x0a, 0x31, 0x73, 0x74, 0x72, 0x61, 0x77, 0x68, 0x61, 0x74, 0x2f, 0x6f, 0x6e, 0x65, 0x70, 0x69,
+	0x65, 0x63, 0x65, 0x2f, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x69, 0x6e,
+	0x67, 0x2f, 0x76, 0x31, 0x2f, 0x64, 0x69, 0x73, 0x70, 0x61, 0x74, 0x63, 0x68, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x22, 0x73, 0x74, 0x72, 0x61, 0x77, 0x68, 0x61, 0x74, 0x2e, 0x6f, 0x6e,
+	0x65, 0x70, 0x69, 0x65, 0x63, 0x65, 0x2e, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x6f, 0x75, 0x72,
+	0x63, 0x69, 0x6e, 0x67, 0x2e, 0x76, 0x31, 0x1a, 0x19, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x61, 0x6e, 0x79, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x22, 0x72, 0x0a, 0x0e, 0x44, 0x69, 0x73, 0x70, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65,
+	0x73, 0x75, 0x6c, 0x74, 0x12, 0x32, 0x0a, 0x15, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x65, 0x78, 0x70,
+	0x65, 0x63, 0x74, 0x65, 0x64, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x13, 0x6e, 0x65, 0x78, 0x74, 0x45, 0x78, 0x70, 0x65, 0x63, 0x74, 0x65,
+	0x64, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x2c, 0x0a, 0x06, 0x65, 0x76, 0x65, 0x6e,
+	0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x41, 0x6e, 0x79, 0x52, 0x06,
+	0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x42, 0x4b, 0x5a, 0x49, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x74, 0x72, 0x61, 0x77, 0x2d, 0x68, 0x61, 0x74, 0x2d, 0x74,
+	0x65, 0x61, 0x6d, 0x2f, 0x6f, 0x6e, 0x65, 0x70, 0x69, 0x65, 0x63, 0x65, 0x2f, 0x67, 0x6f, 0x2f,
+	0x6f, 0x6e, 0x65, 0x70, 0x69, 0x65, 0x63, 0x65, 0x2f, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x6f,
+	0x75, 0x72, 0x63, 0x69, 0x6e, 0x67, 0x2f, 0x6f, 0x6e, 0x65, 0x70, 0x69, 0x65, 0x63, 0x65, 0x67,
+	0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_rawDescOnce sync.Once
+	file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_rawDescData = file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_rawDesc
+)
+
+func file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_rawDescGZIP() []byte {
+	file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_rawDescOnce.Do(func() {
+		file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_rawDescData = protoimpl.X.CompressGZIP(file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_rawDescData)
+	})
+	return file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_rawDescData
+}
+
+var file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_goTypes = []interface{}{
+	(*DispatchResult)(nil), // 0: strawhat.onepiece.eventsourcing.v1.DispatchResult
+	(*anypb.Any)(nil),      // 1: google.protobuf.Any
+}
+var file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_depIdxs = []int32{
+	1, // 0: strawhat.onepiece.eventsourcing.v1.DispatchResult.events:type_name -> google.protobuf.Any
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_init() }
+func file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_init() {
+	if File_strawhat_onepiece_eventsourcing_v1_dispatch_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DispatchResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_goTypes,
+		DependencyIndexes: file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_depIdxs,
+		MessageInfos:      file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_msgTypes,
+	}.Build()
+	File_strawhat_onepiece_eventsourcing_v1_dispatch_proto = out.File
+	file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_rawDesc = nil
+	file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_goTypes = nil
+	file_strawhat_onepiece_eventsourcing_v1_dispatch_proto_depIdxs = nil
+}
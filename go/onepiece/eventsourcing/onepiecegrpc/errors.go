@@ -0,0 +1,97 @@
+package onepiecegrpc
+
+import (
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorInfoDomain identifies the ErrorInfo details carrying a onepiece.DomainError.
+const errorInfoDomain = "onepiece"
+
+// internalErrorMessage replaces the message of any error that is not a domain error, which may expose internals.
+const internalErrorMessage = "internal error"
+
+// Code returns the gRPC status code matching the ErrorStatus.
+func Code(s onepiece.ErrorStatus) codes.Code {
+	switch s {
+	case onepiece.StatusInvalidArgument:
+		return codes.InvalidArgument
+	case onepiece.StatusNotFound:
+		return codes.NotFound
+	case onepiece.StatusAlreadyExists:
+		return codes.AlreadyExists
+	case onepiece.StatusFailedPrecondition:
+		return codes.FailedPrecondition
+	case onepiece.StatusAborted:
+		return codes.Aborted
+	default:
+		return codes.Unknown
+	}
+}
+
+// ToStatus maps domain errors to the status code of their ErrorStatus, with an ErrorInfo detail carrying the code
+// and details of the error. Any other error becomes an Internal status with a fixed message, so log it beforehand.
+func ToStatus(err error) *status.Status {
+	if s, ok := status.FromError(err); ok {
+		return s
+	}
+
+	domainErr, ok := onepiece.AsDomainError(err)
+	if !ok {
+		return status.New(codes.Internal, internalErrorMessage)
+	}
+
+	s := status.New(Code(domainErr.Status), domainErr.Message)
+	withDetails, err := s.WithDetails(&errdetails.ErrorInfo{
+		Reason:   domainErr.Code,
+		Domain:   errorInfoDomain,
+		Metadata: domainErr.Details,
+	})
+	if err != nil {
+		return s
+	}
+	return withDetails
+}
+
+// FromStatus returns the error carried by a status, resolving domain errors against onepiece.DefaultErrorRegistry so
+// errors.Is matches the errors returned by the deciders.
+func FromStatus(s *status.Status) error {
+	if s.Code() == codes.OK {
+		return nil
+	}
+
+	for _, detail := range s.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != errorInfoDomain {
+			continue
+		}
+
+		return onepiece.ResolveDomainError(&onepiece.DomainError{
+			Code:    info.Reason,
+			Status:  errorStatus(s.Code()),
+			Message: s.Message(),
+			Details: info.Metadata,
+		})
+	}
+
+	return s.Err()
+}
+
+func errorStatus(code codes.Code) onepiece.ErrorStatus {
+	switch code {
+	case codes.InvalidArgument:
+		return onepiece.StatusInvalidArgument
+	case codes.NotFound:
+		return onepiece.StatusNotFound
+	case codes.AlreadyExists:
+		return onepiece.StatusAlreadyExists
+	case codes.FailedPrecondition:
+		return onepiece.StatusFailedPrecondition
+	case codes.Aborted:
+		return onepiece.StatusAborted
+	default:
+		return onepiece.StatusUnknown
+	}
+}
@@ -0,0 +1,79 @@
+package onepiecegrpc
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const instrumentationName = "github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecegrpc"
+
+// Authenticate verifies the credentials of a request, typically found in its incoming metadata, and returns the
+// context the request is handled with, e.g. carrying the authenticated principal.
+type Authenticate func(ctx context.Context, fullMethod string) (context.Context, error)
+
+// UnaryAuthInterceptor rejects the requests failing authentication with an Unauthenticated status, unless the
+// Authenticate function returned a status error of its own, e.g. PermissionDenied.
+func UnaryAuthInterceptor(authenticate Authenticate) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, info.FullMethod)
+		if err != nil {
+			if _, ok := status.FromError(err); ok {
+				return nil, err
+			}
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+// UnaryTracingInterceptor continues the trace found in the incoming metadata with a server span per request. A nil
+// provider or propagator defaults to the global one.
+func UnaryTracingInterceptor(provider trace.TracerProvider, propagator propagation.TextMapPropagator) grpc.UnaryServerInterceptor {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	if propagator == nil {
+		propagator = otel.GetTextMapPropagator()
+	}
+	tracer := provider.Tracer(instrumentationName)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = propagator.Extract(ctx, MetadataCarrier(md))
+		ctx, span := tracer.Start(ctx, info.FullMethod, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, err.Error())
+		}
+		return resp, err
+	}
+}
+
+// MetadataCarrier adapts gRPC metadata to a propagation.TextMapCarrier.
+type MetadataCarrier metadata.MD
+
+func (c MetadataCarrier) Get(key string) string {
+	return first(metadata.MD(c), key)
+}
+
+func (c MetadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
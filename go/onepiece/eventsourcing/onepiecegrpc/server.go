@@ -0,0 +1,161 @@
+package onepiecegrpc
+
+import (
+	"context"
+	"errors"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"log/slog"
+	"strings"
+)
+
+const dispatchMethodName = "Dispatch"
+
+// Metadata keys mapped into the eventsourcing.Options of a command. Every key starting with MetadataPrefix becomes a
+// metadata entry named after the rest of the key, lowercased like every gRPC metadata key.
+const (
+	MetadataExpectedRevision = "onepiece-expected-revision"
+	MetadataCorrelationId    = "onepiece-correlation-id"
+	MetadataCausationId      = "onepiece-causation-id"
+	MetadataPrefix           = "onepiece-metadata-"
+)
+
+var ErrServiceNameRequired = errors.New("service name is required")
+
+// NewServiceDesc describes a gRPC service with a single Dispatch(Command) returns (DispatchResult) method, served by
+// the CommandHandler. The serviceName is the full name of the service, e.g. com.hmbradley.deposit.plan.PlanService.
+func NewServiceDesc[Command proto.Message, Event proto.Message](
+	serviceName string,
+	store eventsourcing.EventStore,
+	handler eventsourcing.CommandHandler[Command, Event],
+) *grpc.ServiceDesc {
+	dispatch := func(ctx context.Context, req any) (any, error) {
+		opts, err := OptionsFromMetadata(ctx)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		result, err := handler(ctx, store, req.(Command), opts)
+		if err != nil {
+			s := ToStatus(err)
+			if s.Code() == codes.Internal {
+				slog.ErrorContext(ctx, "command dispatch failed",
+					slog.String("method", DispatchMethod(serviceName)),
+					slog.Any("error", err),
+				)
+			}
+			return nil, s.Err()
+		}
+
+		return newDispatchResult(result)
+	}
+
+	return &grpc.ServiceDesc{
+		ServiceName: serviceName,
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: dispatchMethodName,
+				Handler: func(_ any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+					var command Command
+					command = command.ProtoReflect().Type().New().Interface().(Command)
+					if err := dec(command); err != nil {
+						return nil, err
+					}
+					if interceptor == nil {
+						return dispatch(ctx, command)
+					}
+					info := &grpc.UnaryServerInfo{FullMethod: DispatchMethod(serviceName)}
+					return interceptor(ctx, command, info, dispatch)
+				},
+			},
+		},
+	}
+}
+
+// RegisterService registers the service described by NewServiceDesc.
+func RegisterService[Command proto.Message, Event proto.Message](
+	registrar grpc.ServiceRegistrar,
+	serviceName string,
+	store eventsourcing.EventStore,
+	handler eventsourcing.CommandHandler[Command, Event],
+) error {
+	if serviceName == "" {
+		return ErrServiceNameRequired
+	}
+	registrar.RegisterService(NewServiceDesc(serviceName, store, handler), struct{}{})
+	return nil
+}
+
+// DispatchMethod returns the full method name of the Dispatch method of a service.
+func DispatchMethod(serviceName string) string {
+	return "/" + serviceName + "/" + dispatchMethodName
+}
+
+// OptionsFromMetadata reads the eventsourcing.Options of a command from the incoming metadata. Missing keys leave the
+// matching option unset, so the CommandHandler falls back to its defaults.
+func OptionsFromMetadata(ctx context.Context) (*eventsourcing.Options, error) {
+	opts := &eventsourcing.Options{}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return opts, nil
+	}
+
+	if value := first(md, MetadataExpectedRevision); value != "" {
+		revision, err := eventsourcing.ParseExpectedRevision(value)
+		if err != nil {
+			return nil, err
+		}
+		opts.ExpectedRevision = revision
+	}
+	if value := first(md, MetadataCorrelationId); value != "" {
+		id := eventsourcing.CorrelationId(value)
+		opts.CorrelationId = &id
+	}
+	if value := first(md, MetadataCausationId); value != "" {
+		id := eventsourcing.CausationId(value)
+		opts.CausationId = &id
+	}
+
+	for key, values := range md {
+		name, ok := strings.CutPrefix(key, MetadataPrefix)
+		if !ok || name == "" || len(values) == 0 {
+			continue
+		}
+		if opts.Metadata == nil {
+			opts.Metadata = make(eventsourcing.Metadata)
+		}
+		opts.Metadata[name] = values[0]
+	}
+
+	return opts, nil
+}
+
+func newDispatchResult[Event proto.Message](result *eventsourcing.Result[Event]) (*DispatchResult, error) {
+	events := make([]*anypb.Any, len(result.Events))
+	for i, event := range result.Events {
+		packed, err := anypb.New(event)
+		if err != nil {
+			return nil, err
+		}
+		events[i] = packed
+	}
+
+	return &DispatchResult{
+		NextExpectedVersion: result.NextExpectedVersion,
+		Events:              events,
+	}, nil
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,168 @@
+package onepiecegrpc_test
+
+import (
+	"context"
+	"errors"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecegrpc"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"net"
+	"testing"
+)
+
+const serviceName = "onepiece.test.ValueService"
+
+var errNotFound = onepiece.NewDomainError("thing_not_found", onepiece.StatusNotFound, "thing not found")
+
+type recorder struct {
+	command *structpb.Value
+	opts    *eventsourcing.Options
+	err     error
+}
+
+func (r *recorder) handle(_ context.Context, _ eventsourcing.EventStore, command *structpb.Value, opts *eventsourcing.Options) (*eventsourcing.Result[*structpb.Value], error) {
+	r.command = command
+	r.opts = opts
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &eventsourcing.Result[*structpb.Value]{
+		NextExpectedVersion: 4,
+		Events:              []*structpb.Value{structpb.NewStringValue("created")},
+	}, nil
+}
+
+func dial(t *testing.T, rec *recorder, serverOptions ...grpc.ServerOption) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(serverOptions...)
+	require.NoError(t, onepiecegrpc.RegisterService[*structpb.Value, *structpb.Value](srv, serviceName, nil, rec.handle))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func TestDispatch(t *testing.T) {
+	rec := &recorder{}
+	conn := dial(t, rec)
+	command := structpb.NewStringValue("create")
+
+	t.Run("dispatches the command with its options", func(t *testing.T) {
+		rec.err = nil
+		correlationId := eventsourcing.CorrelationId("correlation")
+		result, err := onepiecegrpc.Dispatch(context.Background(), conn, serviceName, command, &eventsourcing.Options{
+			ExpectedRevision: eventsourcing.Revision(3),
+			CorrelationId:    &correlationId,
+			Metadata:         eventsourcing.Metadata{"Tenant": "acme"},
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, uint64(4), result.NextExpectedVersion)
+		require.Len(t, result.Events, 1)
+		event, err := result.Events[0].UnmarshalNew()
+		require.NoError(t, err)
+		require.True(t, proto.Equal(structpb.NewStringValue("created"), event))
+
+		require.True(t, proto.Equal(command, rec.command))
+		require.Equal(t, &eventsourcing.Options{
+			ExpectedRevision: eventsourcing.Revision(3),
+			CorrelationId:    &correlationId,
+			Metadata:         eventsourcing.Metadata{"tenant": "acme"},
+		}, rec.opts)
+	})
+
+	t.Run("maps domain errors to status codes", func(t *testing.T) {
+		rec.err = errNotFound.WithDetails(map[string]string{"id": "42"})
+		_, err := onepiecegrpc.Dispatch(context.Background(), conn, serviceName, command, nil)
+
+		require.ErrorIs(t, err, errNotFound)
+		domainErr, ok := onepiece.AsDomainError(err)
+		require.True(t, ok)
+		require.Equal(t, onepiece.StatusNotFound, domainErr.Status)
+		require.Equal(t, map[string]string{"id": "42"}, domainErr.Details)
+	})
+
+	t.Run("resolves registered sentinel errors", func(t *testing.T) {
+		rec.err = eventsourcing.ErrOptimisticConcurrency
+		_, err := onepiecegrpc.Dispatch(context.Background(), conn, serviceName, command, nil)
+
+		require.ErrorIs(t, err, eventsourcing.ErrOptimisticConcurrency)
+	})
+
+	t.Run("reports other errors as internal", func(t *testing.T) {
+		rec.err = errors.New("connection refused")
+		_, err := onepiecegrpc.Dispatch(context.Background(), conn, serviceName, command, nil)
+
+		require.Equal(t, codes.Internal, status.Code(err))
+		require.Equal(t, "internal error", status.Convert(err).Message())
+	})
+
+	t.Run("rejects invalid expected revisions", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), onepiecegrpc.MetadataExpectedRevision, "latest")
+		_, err := onepiecegrpc.Dispatch(ctx, conn, serviceName, command, nil)
+
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestInterceptors(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	rec := &recorder{}
+	conn := dial(t, rec, grpc.ChainUnaryInterceptor(
+		onepiecegrpc.UnaryTracingInterceptor(provider, propagation.TraceContext{}),
+		onepiecegrpc.UnaryAuthInterceptor(func(ctx context.Context, fullMethod string) (context.Context, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			switch token := md.Get("authorization"); {
+			case len(token) == 0:
+				return nil, errors.New("missing token")
+			case token[0] != "secret":
+				return nil, status.Error(codes.PermissionDenied, "invalid token")
+			}
+			return ctx, nil
+		}),
+	))
+	command := structpb.NewStringValue("create")
+
+	t.Run("rejects unauthenticated requests", func(t *testing.T) {
+		_, err := onepiecegrpc.Dispatch(context.Background(), conn, serviceName, command, nil)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "guess")
+		_, err = onepiecegrpc.Dispatch(ctx, conn, serviceName, command, nil)
+		require.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("traces authenticated requests", func(t *testing.T) {
+		exporter.Reset()
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "secret")
+		_, err := onepiecegrpc.Dispatch(ctx, conn, serviceName, command, nil)
+		require.NoError(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, onepiecegrpc.DispatchMethod(serviceName), spans[0].Name)
+	})
+}
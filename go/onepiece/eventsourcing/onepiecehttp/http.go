@@ -0,0 +1,145 @@
+package onepiecehttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/protobuf"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const commandsPath = "/commands/"
+
+// MaxCommandBytes is the largest request body accepted, larger commands are rejected with 413 Request Entity Too Large.
+const MaxCommandBytes = 1 << 20
+
+var (
+	ErrUnknownCommand   = onepiece.NewDomainError("unknown_command", onepiece.StatusNotFound, "unknown command")
+	ErrCommandTooLarge  = onepiece.NewDomainError("command_too_large", onepiece.StatusInvalidArgument, "command too large")
+	ErrInvalidIfMatch   = onepiece.NewDomainError("invalid_if_match", onepiece.StatusInvalidArgument, "invalid If-Match header")
+	errInternalResponse = onepiece.NewDomainError("internal", onepiece.StatusUnknown, "internal error")
+)
+
+// Response is the body of a successful command.
+type Response struct {
+	NextExpectedVersion uint64            `json:"nextExpectedVersion"`
+	Events              []json.RawMessage `json:"events"`
+}
+
+// NewCommandHandler serves POST /commands/{type}, where type is the short or full name of a variant of the Command
+// oneof and the body is the protojson of that variant. The If-Match header sets the expected revision, either * for
+// an existing stream or the ETag returned by a previous command. Rejections are returned as the JSON of a
+// onepiece.DomainError with the HTTP status of its ErrorStatus.
+func NewCommandHandler[Command proto.Message, Event proto.Message](
+	store eventsourcing.EventStore,
+	handler eventsourcing.CommandHandler[Command, Event],
+) (http.Handler, error) {
+	registry, err := protobuf.NewOneofRegistry[Command]()
+	if err != nil {
+		return nil, err
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		commandType, ok := strings.CutPrefix(r.URL.Path, commandsPath)
+		if !ok || commandType == "" || strings.Contains(commandType, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if _, ok := registry.Lookup(commandType); !ok {
+			writeError(w, ErrUnknownCommand.WithDetails(map[string]string{"type": commandType}))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxCommandBytes))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, ErrCommandTooLarge.WithDetails(map[string]string{
+				"limit": strconv.FormatInt(maxBytesErr.Limit, 10),
+			}))
+			return
+		}
+		if err != nil {
+			writeError(w, eventsourcing.ErrInvalidCommand.WithDetails(map[string]string{"reason": err.Error()}))
+			return
+		}
+		command, err := registry.DecodeJSON(commandType, body)
+		if err != nil {
+			writeError(w, eventsourcing.ErrInvalidCommand.WithDetails(map[string]string{"reason": err.Error()}))
+			return
+		}
+
+		opts := &eventsourcing.Options{}
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+			revision, err := parseIfMatch(ifMatch)
+			if err != nil {
+				writeError(w, ErrInvalidIfMatch.WithDetails(map[string]string{"reason": err.Error()}))
+				return
+			}
+			opts.ExpectedRevision = revision
+		}
+
+		result, err := handler(r.Context(), store, command, opts)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		resp := Response{
+			NextExpectedVersion: result.NextExpectedVersion,
+			Events:              make([]json.RawMessage, len(result.Events)),
+		}
+		for i, event := range result.Events {
+			data, err := protojson.Marshal(event)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			resp.Events[i] = data
+		}
+
+		w.Header().Set("ETag", formatETag(result.NextExpectedVersion))
+		writeJSON(w, http.StatusOK, resp)
+	}), nil
+}
+
+func parseIfMatch(value string) (eventsourcing.ExpectedRevision, error) {
+	if value == "*" {
+		return eventsourcing.StreamExists{}, nil
+	}
+
+	revision, err := strconv.ParseUint(strings.Trim(strings.TrimPrefix(value, "W/"), `"`), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a stream revision", value)
+	}
+	return eventsourcing.Revision(revision), nil
+}
+
+func formatETag(revision uint64) string {
+	return strconv.Quote(strconv.FormatUint(revision, 10))
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	domainErr, ok := onepiece.AsDomainError(err)
+	if !ok {
+		domainErr = errInternalResponse
+	}
+	writeJSON(w, domainErr.Status.HTTPStatus(), domainErr)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
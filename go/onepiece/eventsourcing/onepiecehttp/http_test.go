@@ -0,0 +1,113 @@
+package onepiecehttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecehttp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var errNotFound = onepiece.NewDomainError("thing_not_found", onepiece.StatusNotFound, "thing not found")
+
+type recorder struct {
+	command *structpb.Value
+	opts    *eventsourcing.Options
+	err     error
+}
+
+func (r *recorder) handle(_ context.Context, _ eventsourcing.EventStore, command *structpb.Value, opts *eventsourcing.Options) (*eventsourcing.Result[*structpb.Value], error) {
+	r.command = command
+	r.opts = opts
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &eventsourcing.Result[*structpb.Value]{
+		NextExpectedVersion: 4,
+		Events:              []*structpb.Value{structpb.NewStringValue("created")},
+	}, nil
+}
+
+func TestNewCommandHandler(t *testing.T) {
+	rec := &recorder{}
+	handler, err := onepiecehttp.NewCommandHandler[*structpb.Value, *structpb.Value](nil, rec.handle)
+	require.NoError(t, err)
+
+	serve := func(method string, target string, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	requireError := func(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+		t.Helper()
+		require.Equal(t, status, w.Code)
+		var body onepiece.DomainError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		require.Equal(t, code, body.Code)
+	}
+
+	t.Run("dispatches the command", func(t *testing.T) {
+		rec.err = nil
+		w := serve(http.MethodPost, "/commands/ListValue", `[1]`, http.Header{"If-Match": {`"3"`}})
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, `"4"`, w.Header().Get("ETag"))
+		require.JSONEq(t, `{"nextExpectedVersion": 4, "events": ["created"]}`, w.Body.String())
+
+		want := structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{structpb.NewNumberValue(1)}})
+		require.True(t, proto.Equal(want, rec.command))
+		require.Equal(t, eventsourcing.Revision(3), rec.opts.ExpectedRevision)
+	})
+
+	t.Run("expects an existing stream for a wildcard If-Match", func(t *testing.T) {
+		rec.err = nil
+		w := serve(http.MethodPost, "/commands/google.protobuf.Struct", `{}`, http.Header{"If-Match": {"*"}})
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, eventsourcing.StreamExists{}, rec.opts.ExpectedRevision)
+	})
+
+	t.Run("maps domain errors to status codes", func(t *testing.T) {
+		rec.err = errNotFound
+		requireError(t, serve(http.MethodPost, "/commands/Struct", `{}`, nil), http.StatusNotFound, "thing_not_found")
+
+		rec.err = eventsourcing.ErrOptimisticConcurrency
+		requireError(t, serve(http.MethodPost, "/commands/Struct", `{}`, nil), http.StatusConflict, eventsourcing.ErrorCodeOptimisticConcurrency)
+	})
+
+	t.Run("hides infrastructure errors", func(t *testing.T) {
+		rec.err = errors.New("connection refused")
+		w := serve(http.MethodPost, "/commands/Struct", `{}`, nil)
+
+		requireError(t, w, http.StatusInternalServerError, "internal")
+		require.NotContains(t, w.Body.String(), "connection refused")
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		requireError(t, serve(http.MethodPost, "/commands/Timestamp", `{}`, nil), http.StatusNotFound, "unknown_command")
+		requireError(t, serve(http.MethodPost, "/commands/Struct", `[]`, nil), http.StatusBadRequest, eventsourcing.ErrInvalidCommand.Code)
+		requireError(t, serve(http.MethodPost, "/commands/Struct", `{}`, http.Header{"If-Match": {"latest"}}), http.StatusBadRequest, "invalid_if_match")
+		require.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodGet, "/commands/Struct", "", nil).Code)
+		require.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/events/Struct", `{}`, nil).Code)
+	})
+
+	t.Run("rejects commands larger than the limit", func(t *testing.T) {
+		rec.command = nil
+		body := `{"name": "` + strings.Repeat("a", onepiecehttp.MaxCommandBytes) + `"}`
+		requireError(t, serve(http.MethodPost, "/commands/Struct", body, nil), http.StatusRequestEntityTooLarge, "command_too_large")
+		require.Nil(t, rec.command)
+	})
+}
@@ -0,0 +1,186 @@
+package onepiecemetrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"time"
+)
+
+type Outcome string
+
+const (
+	OutcomeSuccess               = Outcome("success")
+	OutcomeDomainError           = Outcome("domain_error")
+	OutcomeTerminalState         = Outcome("terminal_state")
+	OutcomeOptimisticConcurrency = Outcome("optimistic_concurrency")
+	OutcomeInfrastructureError   = Outcome("infrastructure_error")
+)
+
+const (
+	namespace          = "onepiece"
+	labelCommandType   = "command_type"
+	labelOutcome       = "outcome"
+	labelSubscription  = "subscription"
+	unknownCommandType = "unknown"
+)
+
+type Metrics struct {
+	commands              *prometheus.CounterVec
+	commandDuration       *prometheus.HistogramVec
+	eventsAppended        *prometheus.CounterVec
+	streamLength          *prometheus.HistogramVec
+	replayDuration        *prometheus.HistogramVec
+	subscriptionProcessed *prometheus.CounterVec
+	subscriptionLag       *prometheus.GaugeVec
+}
+
+// NewMetrics creates the command dispatch and subscription collectors and registers them.
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		commands: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "command",
+			Name:      "handled_total",
+			Help:      "Number of commands handled, by command type and outcome.",
+		}, []string{labelCommandType, labelOutcome}),
+		commandDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "command",
+			Name:      "duration_seconds",
+			Help:      "Time spent handling a command, by command type and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{labelCommandType, labelOutcome}),
+		eventsAppended: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "command",
+			Name:      "events_appended_total",
+			Help:      "Number of events appended to the event store, by command type.",
+		}, []string{labelCommandType}),
+		streamLength: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "stream",
+			Name:      "loaded_events",
+			Help:      "Number of events read from the stream to rebuild the state, by command type.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+		}, []string{labelCommandType}),
+		replayDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "stream",
+			Name:      "replay_duration_seconds",
+			Help:      "Time spent reading the stream and evolving the state, by command type.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{labelCommandType}),
+		subscriptionProcessed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "subscription",
+			Name:      "processed_total",
+			Help:      "Number of events processed by a subscription, by outcome.",
+		}, []string{labelSubscription, labelOutcome}),
+		subscriptionLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "subscription",
+			Name:      "lag_events",
+			Help:      "Number of events a subscription is behind the head of its source.",
+		}, []string{labelSubscription}),
+	}
+
+	collectors := []prometheus.Collector{
+		m.commands,
+		m.commandDuration,
+		m.eventsAppended,
+		m.streamLength,
+		m.replayDuration,
+		m.subscriptionProcessed,
+		m.subscriptionLag,
+	}
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func MustNewMetrics(registerer prometheus.Registerer) *Metrics {
+	m, err := NewMetrics(registerer)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+type CommandType[Command any] func(command Command) string
+
+type commandTypeKey struct{}
+
+// Middleware records the outcome, duration and appended events of every command. Errors are classified with the
+// default eventsourcing.ErrorClassifier, unless an eventsourcing.Classify middleware inside this one classifies them
+// first.
+func Middleware[Command any, Event any](m *Metrics, commandType CommandType[Command]) eventsourcing.Middleware[Command, Event] {
+	return func(next eventsourcing.CommandHandler[Command, Event]) eventsourcing.CommandHandler[Command, Event] {
+		return func(ctx context.Context, store eventsourcing.EventStore, command Command, opts *eventsourcing.Options) (*eventsourcing.Result[Event], error) {
+			cmdType := commandType(command)
+			if cmdType == "" {
+				cmdType = unknownCommandType
+			}
+
+			start := time.Now()
+			result, err := next(context.WithValue(ctx, commandTypeKey{}, cmdType), store, command, opts)
+			outcome := OutcomeOf(err)
+
+			m.commands.WithLabelValues(cmdType, string(outcome)).Inc()
+			m.commandDuration.WithLabelValues(cmdType, string(outcome)).Observe(time.Since(start).Seconds())
+			if result != nil {
+				m.eventsAppended.WithLabelValues(cmdType).Add(float64(len(result.Events)))
+			}
+
+			return result, err
+		}
+	}
+}
+
+// ReplayHook returns the option recording the stream length and replay duration of the handler built by
+// eventsourcing.NewDecider. The command type comes from the Middleware wrapping that handler.
+func (m *Metrics) ReplayHook() eventsourcing.DeciderOption {
+	return eventsourcing.WithReplayHook(func(ctx context.Context, replay eventsourcing.Replay) {
+		cmdType, ok := ctx.Value(commandTypeKey{}).(string)
+		if !ok {
+			cmdType = unknownCommandType
+		}
+
+		m.streamLength.WithLabelValues(cmdType).Observe(float64(replay.EventCount))
+		m.replayDuration.WithLabelValues(cmdType).Observe(replay.Duration.Seconds())
+	})
+}
+
+// SubscriptionProcessed records an event processed by a subscription, successfully or not.
+func (m *Metrics) SubscriptionProcessed(subscription string, err error) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeInfrastructureError
+	}
+
+	m.subscriptionProcessed.WithLabelValues(subscription, string(outcome)).Inc()
+}
+
+// SubscriptionLag records how many events a subscription is behind the head of its source.
+func (m *Metrics) SubscriptionLag(subscription string, lag uint64) {
+	m.subscriptionLag.WithLabelValues(subscription).Set(float64(lag))
+}
+
+func OutcomeOf(err error) Outcome {
+	switch eventsourcing.ErrorKindOf(err) {
+	case "":
+		return OutcomeSuccess
+	case eventsourcing.ErrorKindDomain:
+		return OutcomeDomainError
+	case eventsourcing.ErrorKindTerminal:
+		return OutcomeTerminalState
+	case eventsourcing.ErrorKindConcurrency:
+		return OutcomeOptimisticConcurrency
+	default:
+		return OutcomeInfrastructureError
+	}
+}
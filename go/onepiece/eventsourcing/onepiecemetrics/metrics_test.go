@@ -0,0 +1,159 @@
+package onepiecemetrics_test
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecemessage"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecemetrics"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+var errPlanExists = errors.New("plan already exists")
+
+func returning(result *eventsourcing.Result[string], err error) eventsourcing.CommandHandler[string, string] {
+	return func(_ context.Context, _ eventsourcing.EventStore, _ string, _ *eventsourcing.Options) (*eventsourcing.Result[string], error) {
+		return result, err
+	}
+}
+
+func commandType(command string) string {
+	return command
+}
+
+func TestMiddleware(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := onepiecemetrics.MustNewMetrics(registry)
+
+	dispatch := func(handler eventsourcing.CommandHandler[string, string], command string) {
+		_, _ = eventsourcing.Chain(
+			handler,
+			onepiecemetrics.Middleware[string, string](metrics, commandType),
+			eventsourcing.Classify[string, string](eventsourcing.NewErrorClassifier(errPlanExists)),
+		)(context.Background(), nil, command, nil)
+	}
+
+	dispatch(returning(&eventsourcing.Result[string]{Events: []string{"PlanCreated"}}, nil), "CreatePlan")
+	dispatch(returning(nil, errPlanExists), "CreatePlan")
+	dispatch(returning(nil, onepiece.ErrTerminalState), "ArchivePlan")
+	dispatch(returning(nil, eventsourcing.ErrOptimisticConcurrency), "ArchivePlan")
+	dispatch(returning(nil, errors.New("connection refused")), "ArchivePlan")
+
+	expected := `
+# HELP onepiece_command_handled_total Number of commands handled, by command type and outcome.
+# TYPE onepiece_command_handled_total counter
+onepiece_command_handled_total{command_type="ArchivePlan",outcome="infrastructure_error"} 1
+onepiece_command_handled_total{command_type="ArchivePlan",outcome="optimistic_concurrency"} 1
+onepiece_command_handled_total{command_type="ArchivePlan",outcome="terminal_state"} 1
+onepiece_command_handled_total{command_type="CreatePlan",outcome="domain_error"} 1
+onepiece_command_handled_total{command_type="CreatePlan",outcome="success"} 1
+# HELP onepiece_command_events_appended_total Number of events appended to the event store, by command type.
+# TYPE onepiece_command_events_appended_total counter
+onepiece_command_events_appended_total{command_type="CreatePlan"} 1
+`
+	err := testutil.GatherAndCompare(
+		registry,
+		strings.NewReader(expected),
+		"onepiece_command_handled_total",
+		"onepiece_command_events_appended_total",
+	)
+	require.NoError(t, err)
+	require.Equal(t, 5, testutil.CollectAndCount(registry, "onepiece_command_duration_seconds"))
+}
+
+func TestReplayHook(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := onepiecemetrics.MustNewMetrics(registry)
+	store := onepiecetesting.NewMemoryEventStore()
+
+	decider := onepiece.NewDecider(
+		func(state int, command string) ([]string, error) { return []string{"incremented"}, nil },
+		func(state int, event string) int { return state + 1 },
+	)
+	handler := eventsourcing.Chain(
+		eventsourcing.NewDecider(
+			decider,
+			func(command string) (string, error) { return "counter-1", nil },
+			func(event string) (eventsourcing.ContentType, []byte, error) {
+				return eventsourcing.ContentTypeJson, []byte(`"` + event + `"`), nil
+			},
+			func(eventType string, data []byte) (string, error) { return string(data[1 : len(data)-1]), nil },
+			func(event string) (*onepiecemessage.MessageType, error) {
+				eventType := onepiecemessage.MessageType("counter." + event)
+				return &eventType, nil
+			},
+			metrics.ReplayHook(),
+		),
+		onepiecemetrics.Middleware[string, string](metrics, commandType),
+	)
+
+	// NOTE: the three commands load a stream of 0, 1 and 2 events.
+	for i := 0; i < 3; i++ {
+		_, err := handler(context.Background(), store, "Increment", nil)
+		require.NoError(t, err)
+	}
+
+	expected := `
+# HELP onepiece_stream_loaded_events Number of events read from the stream to rebuild the state, by command type.
+# TYPE onepiece_stream_loaded_events histogram
+onepiece_stream_loaded_events_bucket{command_type="Increment",le="1"} 2
+onepiece_stream_loaded_events_bucket{command_type="Increment",le="2"} 3
+onepiece_stream_loaded_events_bucket{command_type="Increment",le="4"} 3
+onepiece_stream_loaded_events_bucket{command_type="Increment",le="8"} 3
+onepiece_stream_loaded_events_bucket{command_type="Increment",le="16"} 3
+onepiece_stream_loaded_events_bucket{command_type="Increment",le="32"} 3
+onepiece_stream_loaded_events_bucket{command_type="Increment",le="64"} 3
+onepiece_stream_loaded_events_bucket{command_type="Increment",le="128"} 3
+onepiece_stream_loaded_events_bucket{command_type="Increment",le="256"} 3
+onepiece_stream_loaded_events_bucket{command_type="Increment",le="512"} 3
+onepiece_stream_loaded_events_bucket{command_type="Increment",le="1024"} 3
+onepiece_stream_loaded_events_bucket{command_type="Increment",le="2048"} 3
+onepiece_stream_loaded_events_bucket{command_type="Increment",le="+Inf"} 3
+onepiece_stream_loaded_events_sum{command_type="Increment"} 3
+onepiece_stream_loaded_events_count{command_type="Increment"} 3
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "onepiece_stream_loaded_events")
+	require.NoError(t, err)
+	require.Equal(t, 1, testutil.CollectAndCount(registry, "onepiece_stream_replay_duration_seconds"))
+}
+
+func TestSubscriptionMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := onepiecemetrics.MustNewMetrics(registry)
+
+	metrics.SubscriptionProcessed("nats-sink", nil)
+	metrics.SubscriptionProcessed("nats-sink", nil)
+	metrics.SubscriptionProcessed("nats-sink", errors.New("nats: timeout"))
+	metrics.SubscriptionLag("nats-sink", 42)
+
+	expected := `
+# HELP onepiece_subscription_processed_total Number of events processed by a subscription, by outcome.
+# TYPE onepiece_subscription_processed_total counter
+onepiece_subscription_processed_total{outcome="infrastructure_error",subscription="nats-sink"} 1
+onepiece_subscription_processed_total{outcome="success",subscription="nats-sink"} 2
+# HELP onepiece_subscription_lag_events Number of events a subscription is behind the head of its source.
+# TYPE onepiece_subscription_lag_events gauge
+onepiece_subscription_lag_events{subscription="nats-sink"} 42
+`
+	err := testutil.GatherAndCompare(
+		registry,
+		strings.NewReader(expected),
+		"onepiece_subscription_processed_total",
+		"onepiece_subscription_lag_events",
+	)
+	require.NoError(t, err)
+}
+
+func TestNewMetricsRejectsDuplicateRegistration(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	onepiecemetrics.MustNewMetrics(registry)
+
+	_, err := onepiecemetrics.NewMetrics(registry)
+	require.Error(t, err)
+}
@@ -0,0 +1,120 @@
+package onepiecesql
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	// postgresUniqueViolation is the SQLSTATE of a unique constraint violation.
+	postgresUniqueViolation = "23505"
+	// sqlitePrimaryKeyViolation is the extended result code SQLITE_CONSTRAINT_PRIMARYKEY.
+	sqlitePrimaryKeyViolation = 1555
+)
+
+// Dialect holds the SQL that differs between databases. Queries are written with ? placeholders and rebound to the
+// placeholders of the Dialect.
+type Dialect struct {
+	// Placeholder returns the bind parameter at the 1-based position.
+	Placeholder func(position int) string
+	// Schema creates the events and outbox tables when they do not exist.
+	Schema []string
+	// IsUniqueViolation reports whether the error is the violation of the primary key of the events, the error of an
+	// append racing another append of the same revision.
+	IsUniqueViolation func(err error) bool
+}
+
+var Postgres = Dialect{
+	Placeholder: func(position int) string {
+		return "$" + strconv.Itoa(position)
+	},
+	Schema: []string{
+		`CREATE TABLE IF NOT EXISTS onepiece_events (
+			stream_id TEXT NOT NULL,
+			revision BIGINT NOT NULL,
+			event_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			content_type INTEGER NOT NULL,
+			data BYTEA,
+			metadata BYTEA,
+			created_at BIGINT NOT NULL,
+			PRIMARY KEY (stream_id, revision)
+		)`,
+		`CREATE TABLE IF NOT EXISTS onepiece_outbox (
+			id BIGSERIAL PRIMARY KEY,
+			stream_id TEXT NOT NULL,
+			revision BIGINT NOT NULL,
+			event_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			content_type INTEGER NOT NULL,
+			data BYTEA,
+			metadata BYTEA,
+			created_at BIGINT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at BIGINT NOT NULL,
+			last_error TEXT,
+			sent_at BIGINT
+		)`,
+		`CREATE INDEX IF NOT EXISTS onepiece_outbox_pending ON onepiece_outbox (id) WHERE sent_at IS NULL`,
+	},
+	// NOTE: both pgx and lib/pq errors expose their SQLSTATE through SQLState, so no driver is imported.
+	IsUniqueViolation: func(err error) bool {
+		var stateErr interface{ SQLState() string }
+		return errors.As(err, &stateErr) && stateErr.SQLState() == postgresUniqueViolation
+	},
+}
+
+var SQLite = Dialect{
+	Placeholder: func(int) string {
+		return "?"
+	},
+	Schema: []string{
+		`CREATE TABLE IF NOT EXISTS onepiece_events (
+			stream_id TEXT NOT NULL,
+			revision INTEGER NOT NULL,
+			event_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			content_type INTEGER NOT NULL,
+			data BLOB,
+			metadata BLOB,
+			created_at INTEGER NOT NULL,
+			PRIMARY KEY (stream_id, revision)
+		)`,
+		`CREATE TABLE IF NOT EXISTS onepiece_outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			stream_id TEXT NOT NULL,
+			revision INTEGER NOT NULL,
+			event_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			content_type INTEGER NOT NULL,
+			data BLOB,
+			metadata BLOB,
+			created_at INTEGER NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			next_attempt_at INTEGER NOT NULL,
+			last_error TEXT,
+			sent_at INTEGER
+		)`,
+		`CREATE INDEX IF NOT EXISTS onepiece_outbox_pending ON onepiece_outbox (id) WHERE sent_at IS NULL`,
+	},
+	// NOTE: the errors of modernc.org/sqlite expose their extended result code through Code.
+	IsUniqueViolation: func(err error) bool {
+		var codeErr interface{ Code() int }
+		return errors.As(err, &codeErr) && codeErr.Code() == sqlitePrimaryKeyViolation
+	},
+}
+
+func (d Dialect) rebind(query string) string {
+	var b strings.Builder
+	position := 0
+	for _, r := range query {
+		if r == '?' {
+			position++
+			b.WriteString(d.Placeholder(position))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
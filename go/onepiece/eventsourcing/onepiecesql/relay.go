@@ -0,0 +1,209 @@
+package onepiecesql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"log/slog"
+	"time"
+)
+
+// OutboxMessage is an event waiting in the outbox to be published.
+type OutboxMessage struct {
+	Id          int64
+	StreamId    string
+	Event       eventsourcing.RecordedEvent
+	ContentType eventsourcing.ContentType
+	CreatedAt   time.Time
+	// Attempts is the number of failed attempts to publish the message so far.
+	Attempts int
+}
+
+// Publisher publishes the messages of the outbox, e.g. to NATS. Messages may be published more than once when the
+// Relay fails to mark them as sent, so publishers should deduplicate them by event id.
+type Publisher interface {
+	Publish(ctx context.Context, message *OutboxMessage) error
+}
+
+type RelayConfig struct {
+	// BatchSize is the maximum number of messages read from the outbox at once. Defaults to 100.
+	BatchSize int
+	// PollInterval is the delay before reading the outbox again once it is drained. Defaults to 1s.
+	PollInterval time.Duration
+	// MinBackoff is the delay before retrying a message after its first failure, doubled on every following failure.
+	// Defaults to 1s.
+	MinBackoff time.Duration
+	// MaxBackoff caps the delay between retries. Defaults to 1m.
+	MaxBackoff time.Duration
+	// Logger reports the messages failing to be published. Defaults to slog.Default.
+	Logger *slog.Logger
+}
+
+// Relay publishes the pending messages of the outbox in order per stream: a message failing to be published holds
+// back the following messages of its stream until it is retried successfully. Run a single Relay per outbox.
+type Relay struct {
+	db        *sql.DB
+	dialect   Dialect
+	publisher Publisher
+	config    RelayConfig
+}
+
+func NewRelay(db *sql.DB, dialect Dialect, publisher Publisher, config RelayConfig) *Relay {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Minute
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	return &Relay{
+		db:        db,
+		dialect:   dialect,
+		publisher: publisher,
+		config:    config,
+	}
+}
+
+// Run relays the outbox until the context is done, which is not reported as an error.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		sent, err := r.RelayPending(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if sent > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.config.PollInterval):
+		}
+	}
+}
+
+// RelayPending publishes a batch of the messages due in the outbox and returns how many were sent.
+func (r *Relay) RelayPending(ctx context.Context) (int, error) {
+	messages, err := r.pending(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	failedStreams := map[string]bool{}
+	for _, message := range messages {
+		if failedStreams[message.StreamId] {
+			continue
+		}
+
+		if err := r.publisher.Publish(ctx, message); err != nil {
+			failedStreams[message.StreamId] = true
+			if err := r.markFailed(ctx, message, err); err != nil {
+				return sent, err
+			}
+			continue
+		}
+
+		if err := r.markSent(ctx, message); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// pending reads the messages due, skipping the streams held back by an earlier message waiting for its retry.
+func (r *Relay) pending(ctx context.Context) ([]*OutboxMessage, error) {
+	now := time.Now().UnixMilli()
+	rows, err := r.db.QueryContext(ctx, r.dialect.rebind(
+		`SELECT id, stream_id, revision, event_id, event_type, content_type, data, metadata, created_at, attempts
+		FROM onepiece_outbox o
+		WHERE sent_at IS NULL AND next_attempt_at <= ? AND NOT EXISTS (
+			SELECT 1 FROM onepiece_outbox p
+			WHERE p.stream_id = o.stream_id AND p.sent_at IS NULL AND p.id < o.id AND p.next_attempt_at > ?
+		)
+		ORDER BY id
+		LIMIT ?`,
+	), now, now, r.config.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*OutboxMessage
+	for rows.Next() {
+		var message OutboxMessage
+		var contentType int
+		var createdAt int64
+		err := rows.Scan(
+			&message.Id,
+			&message.StreamId,
+			&message.Event.Revision,
+			&message.Event.EventId,
+			&message.Event.EventType,
+			&contentType,
+			&message.Event.Data,
+			&message.Event.Metadata,
+			&createdAt,
+			&message.Attempts,
+		)
+		if err != nil {
+			return nil, err
+		}
+		message.ContentType = eventsourcing.ContentType(contentType)
+		message.CreatedAt = time.UnixMilli(createdAt)
+		messages = append(messages, &message)
+	}
+
+	return messages, rows.Err()
+}
+
+func (r *Relay) markSent(ctx context.Context, message *OutboxMessage) error {
+	_, err := r.db.ExecContext(ctx, r.dialect.rebind(
+		`UPDATE onepiece_outbox SET sent_at = ? WHERE id = ?`,
+	), time.Now().UnixMilli(), message.Id)
+	return err
+}
+
+func (r *Relay) markFailed(ctx context.Context, message *OutboxMessage, publishErr error) error {
+	attempts := message.Attempts + 1
+	delay := r.backoff(attempts)
+	r.config.Logger.WarnContext(ctx, "outbox message publishing failed",
+		slog.Int64("outbox_id", message.Id),
+		slog.String(eventsourcing.LogKeyStreamId, message.StreamId),
+		slog.Uint64(eventsourcing.LogKeyRevision, message.Event.Revision),
+		slog.Int("attempts", attempts),
+		slog.Duration("delay", delay),
+		slog.Any("error", publishErr),
+	)
+
+	_, err := r.db.ExecContext(ctx, r.dialect.rebind(
+		`UPDATE onepiece_outbox SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?`,
+	), attempts, time.Now().Add(delay).UnixMilli(), publishErr.Error(), message.Id)
+	return err
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.config.MinBackoff
+	for i := 1; i < attempts && delay < r.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.config.MaxBackoff {
+		delay = r.config.MaxBackoff
+	}
+	return delay
+}
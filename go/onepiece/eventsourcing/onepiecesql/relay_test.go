@@ -0,0 +1,89 @@
+package onepiecesql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecesql"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingPublisher struct {
+	mu        sync.Mutex
+	published []string
+	failures  map[string]int
+}
+
+// Publish fails the messages listed in failures as many times as requested.
+func (p *recordingPublisher) Publish(_ context.Context, message *onepiecesql.OutboxMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := fmt.Sprintf("%s@%d", message.StreamId, message.Event.Revision)
+	if p.failures[key] > 0 {
+		p.failures[key]--
+		return errors.New("nats unavailable")
+	}
+	p.published = append(p.published, key)
+	return nil
+}
+
+func (p *recordingPublisher) Published() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.published...)
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	db, store := newStore(t)
+
+	for _, command := range []increment{{"a", 1}, {"a", 2}, {"b", 1}} {
+		_, err := dispatchIncrement(ctx, store, command, nil)
+		require.NoError(t, err)
+	}
+
+	publisher := &recordingPublisher{failures: map[string]int{"counter-a@0": 1}}
+	relay := onepiecesql.NewRelay(db, onepiecesql.SQLite, publisher, onepiecesql.RelayConfig{
+		MinBackoff: 20 * time.Millisecond,
+	})
+
+	t.Run("holds back the stream of a failed message", func(t *testing.T) {
+		sent, err := relay.RelayPending(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, sent)
+		require.Equal(t, []string{"counter-b@0"}, publisher.Published())
+
+		sent, err = relay.RelayPending(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0, sent)
+	})
+
+	t.Run("retries in order after the backoff", func(t *testing.T) {
+		time.Sleep(30 * time.Millisecond)
+
+		sent, err := relay.RelayPending(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 2, sent)
+		require.Equal(t, []string{"counter-b@0", "counter-a@0", "counter-a@1"}, publisher.Published())
+	})
+
+	t.Run("runs until the context is done", func(t *testing.T) {
+		_, err := dispatchIncrement(ctx, store, increment{"c", 1}, nil)
+		require.NoError(t, err)
+
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() { done <- relay.Run(runCtx) }()
+
+		require.Eventually(t, func() bool {
+			return len(publisher.Published()) == 4
+		}, 5*time.Second, 10*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+}
@@ -0,0 +1,162 @@
+package onepiecesql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"time"
+)
+
+// Store is an eventsourcing.EventStore backed by a SQL database. Every appended event is written to the outbox in the
+// same transaction, to be published by a Relay.
+type Store struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+func NewStore(db *sql.DB, dialect Dialect) *Store {
+	return &Store{db: db, dialect: dialect}
+}
+
+// Migrate creates the events and outbox tables when they do not exist.
+func (s *Store) Migrate(ctx context.Context) error {
+	for _, statement := range s.dialect.Schema {
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) ReadStream(ctx context.Context, streamId string) ([]eventsourcing.RecordedEvent, error) {
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(
+		`SELECT event_id, event_type, data, metadata, revision FROM onepiece_events WHERE stream_id = ? ORDER BY revision`,
+	), streamId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []eventsourcing.RecordedEvent
+	for rows.Next() {
+		var event eventsourcing.RecordedEvent
+		if err := rows.Scan(&event.EventId, &event.EventType, &event.Data, &event.Metadata, &event.Revision); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (s *Store) AppendToStream(
+	ctx context.Context,
+	streamId string,
+	expectedRevision eventsourcing.ExpectedRevision,
+	events []eventsourcing.EventData,
+) (*eventsourcing.WriteResult, error) {
+	result, err := s.append(ctx, streamId, expectedRevision, events)
+	if err != nil && s.dialect.IsUniqueViolation != nil && s.dialect.IsUniqueViolation(err) {
+		// A concurrent append of the same revision fails on the primary key of the events.
+		return nil, eventsourcing.ErrOptimisticConcurrency
+	}
+	return result, err
+}
+
+func (s *Store) append(
+	ctx context.Context,
+	streamId string,
+	expectedRevision eventsourcing.ExpectedRevision,
+	events []eventsourcing.EventData,
+) (_ *eventsourcing.WriteResult, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, tx.Rollback())
+		}
+	}()
+
+	current, err := s.currentRevision(ctx, tx, streamId)
+	if err != nil {
+		return nil, err
+	}
+	if !matchesRevision(expectedRevision, current) {
+		return nil, eventsourcing.ErrOptimisticConcurrency
+	}
+
+	next := uint64(0)
+	if current.Valid {
+		next = uint64(current.Int64) + 1
+	}
+	now := time.Now().UnixMilli()
+
+	for _, event := range events {
+		args := []any{
+			streamId,
+			next,
+			event.EventID.String(),
+			event.EventType,
+			int(event.ContentType),
+			event.Data,
+			event.Metadata,
+			now,
+		}
+
+		_, err := tx.ExecContext(ctx, s.dialect.rebind(
+			`INSERT INTO onepiece_events (stream_id, revision, event_id, event_type, content_type, data, metadata, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		), args...)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.ExecContext(ctx, s.dialect.rebind(
+			`INSERT INTO onepiece_outbox (stream_id, revision, event_id, event_type, content_type, data, metadata, created_at, next_attempt_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		), append(args, now)...)
+		if err != nil {
+			return nil, err
+		}
+
+		next++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	result := &eventsourcing.WriteResult{}
+	if next > 0 {
+		result.NextExpectedVersion = next - 1
+	}
+	return result, nil
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (s *Store) currentRevision(ctx context.Context, q queryer, streamId string) (sql.NullInt64, error) {
+	var revision sql.NullInt64
+	err := q.QueryRowContext(ctx, s.dialect.rebind(
+		`SELECT MAX(revision) FROM onepiece_events WHERE stream_id = ?`,
+	), streamId).Scan(&revision)
+	return revision, err
+}
+
+func matchesRevision(expected eventsourcing.ExpectedRevision, current sql.NullInt64) bool {
+	switch r := expected.(type) {
+	case eventsourcing.NoStream:
+		return !current.Valid
+	case eventsourcing.StreamExists:
+		return current.Valid
+	case eventsourcing.StreamRevision:
+		return current.Valid && uint64(current.Int64) == r.Value
+	default:
+		return true
+	}
+}
@@ -0,0 +1,186 @@
+package onepiecesql_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/gofrs/uuid"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecemessage"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecesql"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+	"path/filepath"
+	"testing"
+)
+
+type increment struct {
+	CounterId string
+	Amount    int
+}
+
+// dispatchIncrement counts the increments of a counter, one event per increment.
+var dispatchIncrement = eventsourcing.NewDecider[int, increment, int](
+	onepiece.NewDecider(
+		func(_ int, command increment) ([]int, error) {
+			return []int{command.Amount}, nil
+		},
+		func(state int, event int) int {
+			return state + event
+		},
+	),
+	func(command increment) (string, error) {
+		return "counter-" + command.CounterId, nil
+	},
+	func(event int) (eventsourcing.ContentType, []byte, error) {
+		data, err := json.Marshal(event)
+		return eventsourcing.ContentTypeJson, data, err
+	},
+	func(_ string, data []byte) (int, error) {
+		var event int
+		err := json.Unmarshal(data, &event)
+		return event, err
+	},
+	func(int) (*onepiecemessage.MessageType, error) {
+		return onepiecemessage.NewMessageType("test.onepiece.counter.v1.Incremented")
+	},
+)
+
+func newStore(t *testing.T) (*sql.DB, *onepiecesql.Store) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "events.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	store := onepiecesql.NewStore(db, onepiecesql.SQLite)
+	require.NoError(t, store.Migrate(context.Background()))
+	return db, store
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	_, store := newStore(t)
+
+	t.Run("appends the events decided by a command handler", func(t *testing.T) {
+		result, err := dispatchIncrement(ctx, store, increment{CounterId: "a", Amount: 1}, nil)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), result.NextExpectedVersion)
+
+		result, err = dispatchIncrement(ctx, store, increment{CounterId: "a", Amount: 2}, nil)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), result.NextExpectedVersion)
+
+		events, err := store.ReadStream(ctx, "counter-a")
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Equal(t, "test.onepiece.counter.v1.Incremented", events[1].EventType)
+		require.Equal(t, []byte("2"), events[1].Data)
+		require.Equal(t, uint64(1), events[1].Revision)
+		require.NotEmpty(t, events[1].EventId)
+		require.Contains(t, string(events[1].Metadata), "$correlationId")
+	})
+
+	t.Run("reads missing streams as empty", func(t *testing.T) {
+		events, err := store.ReadStream(ctx, "counter-missing")
+		require.NoError(t, err)
+		require.Empty(t, events)
+	})
+
+	t.Run("rejects unexpected revisions", func(t *testing.T) {
+		conflicts := []eventsourcing.ExpectedRevision{
+			eventsourcing.NoStream{},
+			eventsourcing.Revision(0),
+		}
+		for _, revision := range conflicts {
+			_, err := dispatchIncrement(ctx, store, increment{CounterId: "a", Amount: 1}, &eventsourcing.Options{ExpectedRevision: revision})
+			require.ErrorIs(t, err, eventsourcing.ErrOptimisticConcurrency)
+		}
+
+		_, err := dispatchIncrement(ctx, store, increment{CounterId: "b", Amount: 1}, &eventsourcing.Options{ExpectedRevision: eventsourcing.StreamExists{}})
+		require.ErrorIs(t, err, eventsourcing.ErrOptimisticConcurrency)
+
+		result, err := dispatchIncrement(ctx, store, increment{CounterId: "a", Amount: 1}, &eventsourcing.Options{ExpectedRevision: eventsourcing.Revision(1)})
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), result.NextExpectedVersion)
+	})
+}
+
+func TestStoreErrors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("returns other errors as they are", func(t *testing.T) {
+		db, store := newStore(t)
+		_, err := db.ExecContext(ctx, `DROP TABLE onepiece_outbox`)
+		require.NoError(t, err)
+
+		_, err = dispatchIncrement(ctx, store, increment{CounterId: "a", Amount: 1}, nil)
+		require.Error(t, err)
+		require.NotErrorIs(t, err, eventsourcing.ErrOptimisticConcurrency)
+		require.Contains(t, err.Error(), "onepiece_outbox")
+	})
+
+	t.Run("writes neither events nor outbox rows when the outbox insert fails", func(t *testing.T) {
+		db, store := newStore(t)
+		// NOTE: the second event is inserted before its outbox row fails, after the first event and outbox row.
+		_, err := db.ExecContext(ctx, `CREATE TRIGGER fail_outbox BEFORE INSERT ON onepiece_outbox
+			WHEN NEW.revision = 1 BEGIN SELECT RAISE(ABORT, 'outbox unavailable'); END`)
+		require.NoError(t, err)
+
+		events := make([]eventsourcing.EventData, 2)
+		for i := range events {
+			events[i] = eventsourcing.EventData{
+				EventID:     uuid.Must(uuid.NewV4()),
+				EventType:   "test.onepiece.counter.v1.Incremented",
+				ContentType: eventsourcing.ContentTypeJson,
+				Data:        []byte("1"),
+			}
+		}
+		_, err = store.AppendToStream(ctx, "counter-a", eventsourcing.NoStream{}, events)
+		require.ErrorContains(t, err, "outbox unavailable")
+
+		stored, err := store.ReadStream(ctx, "counter-a")
+		require.NoError(t, err)
+		require.Empty(t, stored)
+
+		var outboxRows int
+		require.NoError(t, db.QueryRowContext(ctx, `SELECT COUNT(*) FROM onepiece_outbox`).Scan(&outboxRows))
+		require.Zero(t, outboxRows)
+	})
+}
+
+// sqlStateError is the SQLState method shared by the pgx and lib/pq errors.
+type sqlStateError string
+
+func (e sqlStateError) Error() string {
+	return "postgres error " + string(e)
+}
+
+func (e sqlStateError) SQLState() string {
+	return string(e)
+}
+
+func TestDialectIsUniqueViolation(t *testing.T) {
+	t.Run("sqlite", func(t *testing.T) {
+		db, _ := newStore(t)
+		insert := `INSERT INTO onepiece_events (stream_id, revision, event_id, event_type, content_type, created_at)
+			VALUES ('counter-a', 0, 'e1', 'Incremented', 1, 0)`
+		_, err := db.ExecContext(context.Background(), insert)
+		require.NoError(t, err)
+
+		_, err = db.ExecContext(context.Background(), insert)
+		require.True(t, onepiecesql.SQLite.IsUniqueViolation(err))
+
+		_, err = db.ExecContext(context.Background(), `INSERT INTO onepiece_events (stream_id) VALUES ('counter-b')`)
+		require.Error(t, err)
+		require.False(t, onepiecesql.SQLite.IsUniqueViolation(err))
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		require.True(t, onepiecesql.Postgres.IsUniqueViolation(errors.Join(sqlStateError("23505"), errors.New("rollback"))))
+		require.False(t, onepiecesql.Postgres.IsUniqueViolation(sqlStateError("23502")))
+		require.False(t, onepiecesql.Postgres.IsUniqueViolation(errors.New("connection refused")))
+	})
+}
@@ -0,0 +1,76 @@
+package onepiecetesting
+
+import (
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"github.com/straw-hat-team/onepiece/go/onepiece/protobuf"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protocmp"
+)
+
+// EventMatcher returns the diff between an expected event and the actual one, empty when the event matches. The
+// options are the ones of the TestCase, protocmp.Transform included.
+type EventMatcher func(actual any, options ...cmp.Option) string
+
+// Equals matches an event equal to the expected one, like Then.
+func Equals(expected any) EventMatcher {
+	return func(actual any, options ...cmp.Option) string {
+		return cmp.Diff(expected, actual, options...)
+	}
+}
+
+// Partial matches a protobuf event with the same type as the partial message and the same value for every field set in
+// it, so Partial(&planproto.PlanDrained{PlanId: id}) ignores every other field of the event. The partial message may be
+// the variant of an Event oneof. Fields set to their zero value are not compared, since protobuf can not tell them from
+// unset fields.
+func Partial(partial proto.Message) EventMatcher {
+	name := partial.ProtoReflect().Descriptor().FullName()
+
+	return func(actual any, options ...cmp.Option) string {
+		msg, ok := actual.(proto.Message)
+		if !ok {
+			return fmt.Sprintf("want a %s, got a %T", name, actual)
+		}
+		if msg.ProtoReflect().Descriptor().FullName() != name {
+			if variant, err := protobuf.OneofValue(msg); err == nil {
+				msg = variant
+			}
+		}
+		if actualName := msg.ProtoReflect().Descriptor().FullName(); actualName != name {
+			return fmt.Sprintf("want a %s, got a %s", name, actualName)
+		}
+
+		masked := proto.Clone(msg)
+		maskUnset(partial.ProtoReflect(), masked.ProtoReflect())
+		return cmp.Diff(partial, masked, options...)
+	}
+}
+
+// maskUnset clears the fields of the actual message that are not set in the partial one, recursively in nested
+// messages.
+func maskUnset(partial protoreflect.Message, actual protoreflect.Message) {
+	var unset []protoreflect.FieldDescriptor
+	var nested []protoreflect.FieldDescriptor
+	actual.Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		switch {
+		case !partial.Has(field):
+			unset = append(unset, field)
+		case field.Message() != nil && !field.IsList() && !field.IsMap():
+			nested = append(nested, field)
+		}
+		return true
+	})
+
+	for _, field := range unset {
+		actual.Clear(field)
+	}
+	for _, field := range nested {
+		maskUnset(partial.Get(field).Message(), actual.Mutable(field).Message())
+	}
+}
+
+// IgnoreFields ignores the fields of a protobuf message with the given names, such as generated ids and timestamps.
+func IgnoreFields(message proto.Message, names ...protoreflect.Name) cmp.Option {
+	return protocmp.IgnoreFields(message, names...)
+}
@@ -0,0 +1,86 @@
+package onepiecetesting_test
+
+import (
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"testing"
+)
+
+func TestPartial(t *testing.T) {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("plan_id"),
+		Number:   proto.Int32(1),
+		JsonName: proto.String("planId"),
+		Options:  &descriptorpb.FieldOptions{Deprecated: proto.Bool(true), Lazy: proto.Bool(true)},
+	}
+	transform := protocmp.Transform()
+
+	t.Run("compares only the fields set in the partial message", func(t *testing.T) {
+		matcher := onepiecetesting.Partial(&descriptorpb.FieldDescriptorProto{
+			Name:    proto.String("plan_id"),
+			Options: &descriptorpb.FieldOptions{Lazy: proto.Bool(true)},
+		})
+		require.Empty(t, matcher(field, transform))
+	})
+
+	t.Run("reports the fields with another value", func(t *testing.T) {
+		matcher := onepiecetesting.Partial(&descriptorpb.FieldDescriptorProto{Name: proto.String("title")})
+		diff := matcher(field, transform)
+		require.Contains(t, diff, `"title"`)
+		require.Contains(t, diff, `"plan_id"`)
+	})
+
+	t.Run("does not modify the actual message", func(t *testing.T) {
+		onepiecetesting.Partial(&descriptorpb.FieldDescriptorProto{Name: proto.String("plan_id")})(field, transform)
+		require.Equal(t, "planId", field.GetJsonName())
+	})
+
+	t.Run("matches the variant of a oneof", func(t *testing.T) {
+		value := structpb.NewListValue(&structpb.ListValue{})
+		require.Empty(t, onepiecetesting.Partial(&structpb.ListValue{})(value, transform))
+		require.Contains(t, onepiecetesting.Partial(&structpb.Struct{})(value, transform), "want a google.protobuf.Struct")
+	})
+}
+
+// fieldDecider decides every command with the given events.
+func fieldDecider(events ...*descriptorpb.FieldDescriptorProto) *onepiece.Decider[int, string, *descriptorpb.FieldDescriptorProto] {
+	return onepiece.NewDecider(
+		func(int, string) ([]*descriptorpb.FieldDescriptorProto, error) { return events, nil },
+		func(state int, _ *descriptorpb.FieldDescriptorProto) int { return state + 1 },
+	)
+}
+
+func TestTestCaseOptions(t *testing.T) {
+	incremented := func(name string, number int32) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number)}
+	}
+	t.Run("ignores fields", func(t *testing.T) {
+		onepiecetesting.NewTestCase(t, fieldDecider(incremented("counter", 42))).
+			When("increment").
+			Then(incremented("counter", 1)).
+			WithOptions(onepiecetesting.IgnoreFields(&descriptorpb.FieldDescriptorProto{}, "number")).
+			Assert()
+	})
+
+	t.Run("matches events partially", func(t *testing.T) {
+		onepiecetesting.NewTestCase(t, fieldDecider(incremented("counter", 42))).
+			When("increment").
+			ThenMatching(onepiecetesting.Partial(&descriptorpb.FieldDescriptorProto{Name: proto.String("counter")})).
+			Assert()
+	})
+
+	t.Run("mixes exact and partial matchers", func(t *testing.T) {
+		onepiecetesting.NewTestCase(t, fieldDecider(incremented("counter", 42), incremented("total", 7))).
+			When("increment").
+			ThenMatching(
+				onepiecetesting.Equals(incremented("counter", 42)),
+				onepiecetesting.Partial(&descriptorpb.FieldDescriptorProto{Number: proto.Int32(7)}),
+			).
+			Assert()
+	})
+}
@@ -0,0 +1,238 @@
+package onepiecetesting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/protobuf"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type CoverageFormat string
+
+const (
+	CoverageFormatText CoverageFormat = "text"
+	CoverageFormatJSON CoverageFormat = "json"
+)
+
+// trackedCoverage holds the Coverage of every decider given to TrackCoverage, by decider.
+var trackedCoverage sync.Map
+
+// Coverage records the commands decided, the events decided and the declared errors returned by the test cases of a
+// decider, across NewTestCase, scenarios and testing files.
+type Coverage struct {
+	mu       sync.Mutex
+	name     string
+	commands coverageSet
+	events   coverageSet
+	errors   map[string]error
+	caught   map[string]int
+}
+
+// coverageSet counts the exercised variants, the declared ones being reported even when never exercised.
+type coverageSet struct {
+	declared []string
+	counts   map[string]int
+}
+
+// TrackCoverage starts recording the coverage of the decider, under the given name. The variants of the Command and
+// Event oneofs are declared from their protobuf descriptors, and the sentinel errors of the decider are declared by name,
+// e.g. {"ErrPlanExists": planactor.ErrPlanExists}. Call it before the tests run, usually from TestMain.
+func TrackCoverage[State any, Command any, Event any](
+	decider *onepiece.Decider[State, Command, Event],
+	name string,
+	declaredErrors map[string]error,
+) *Coverage {
+	coverage := &Coverage{
+		name:     name,
+		commands: coverageSet{declared: oneofTypes[Command](), counts: map[string]int{}},
+		events:   coverageSet{declared: oneofTypes[Event](), counts: map[string]int{}},
+		errors:   map[string]error{},
+		caught:   map[string]int{},
+	}
+	for errorName, err := range declaredErrors {
+		coverage.errors[errorName] = err
+	}
+
+	actual, _ := trackedCoverage.LoadOrStore(decider, coverage)
+	return actual.(*Coverage)
+}
+
+// recordCoverage records a decided command, rejected by a terminal state included, if its decider is tracked.
+func recordCoverage[State any, Command any, Event any](
+	decider *onepiece.Decider[State, Command, Event],
+	command Command,
+	events []Event,
+	err error,
+) {
+	value, ok := trackedCoverage.Load(decider)
+	if !ok {
+		return
+	}
+	coverage := value.(*Coverage)
+
+	coverage.mu.Lock()
+	defer coverage.mu.Unlock()
+
+	coverage.commands.counts[eventsourcing.MessageTypeOf(command)]++
+	for _, event := range events {
+		coverage.events.counts[eventsourcing.MessageTypeOf(event)]++
+	}
+	if err == nil {
+		return
+	}
+	for errorName, declared := range coverage.errors {
+		if errors.Is(err, declared) {
+			coverage.caught[errorName]++
+		}
+	}
+}
+
+// CoverageSection lists the covered and the uncovered names of a kind of message, or of the declared errors.
+type CoverageSection struct {
+	Covered   []string `json:"covered"`
+	Uncovered []string `json:"uncovered"`
+}
+
+// CoverageReport is the coverage of a decider.
+type CoverageReport struct {
+	Decider  string          `json:"decider"`
+	Commands CoverageSection `json:"commands"`
+	Events   CoverageSection `json:"events"`
+	Errors   CoverageSection `json:"errors"`
+}
+
+// Complete reports whether every declared command, event and error is covered.
+func (r CoverageReport) Complete() bool {
+	return len(r.Commands.Uncovered) == 0 && len(r.Events.Uncovered) == 0 && len(r.Errors.Uncovered) == 0
+}
+
+func (r CoverageReport) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s: commands %s, events %s, errors %s\n",
+		r.Decider, r.Commands.ratio(), r.Events.ratio(), r.Errors.ratio())
+	for _, section := range []struct {
+		name    string
+		section CoverageSection
+	}{{"commands", r.Commands}, {"events", r.Events}, {"errors", r.Errors}} {
+		for _, uncovered := range section.section.Uncovered {
+			fmt.Fprintf(&builder, "  uncovered %s: %s\n", section.name, uncovered)
+		}
+	}
+	return builder.String()
+}
+
+func (s CoverageSection) ratio() string {
+	return fmt.Sprintf("%d/%d", len(s.Covered), len(s.Covered)+len(s.Uncovered))
+}
+
+// Report returns the coverage recorded so far.
+func (c *Coverage) Report() CoverageReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	declaredErrors := make([]string, 0, len(c.errors))
+	for errorName := range c.errors {
+		declaredErrors = append(declaredErrors, errorName)
+	}
+
+	return CoverageReport{
+		Decider:  c.name,
+		Commands: c.commands.section(),
+		Events:   c.events.section(),
+		Errors:   coverageSet{declared: declaredErrors, counts: c.caught}.section(),
+	}
+}
+
+func (s coverageSet) section() CoverageSection {
+	section := CoverageSection{Covered: []string{}, Uncovered: []string{}}
+	for name := range s.counts {
+		section.Covered = append(section.Covered, name)
+	}
+	for _, name := range s.declared {
+		if s.counts[name] == 0 && !slices.Contains(section.Uncovered, name) {
+			section.Uncovered = append(section.Uncovered, name)
+		}
+	}
+	sort.Strings(section.Covered)
+	sort.Strings(section.Uncovered)
+	return section
+}
+
+// CoverageReports returns the report of every tracked decider, by name.
+func CoverageReports() []CoverageReport {
+	var reports []CoverageReport
+	trackedCoverage.Range(func(_, value any) bool {
+		reports = append(reports, value.(*Coverage).Report())
+		return true
+	})
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Decider < reports[j].Decider })
+	return reports
+}
+
+// WriteCoverage writes the report of every tracked decider.
+func WriteCoverage(w io.Writer, format CoverageFormat) error {
+	reports := CoverageReports()
+
+	switch format {
+	case CoverageFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(reports)
+	case CoverageFormatText:
+		for _, report := range reports {
+			if _, err := io.WriteString(w, report.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown coverage format %q", format)
+	}
+}
+
+// WriteCoverageFile writes the report of every tracked decider to the file, as JSON when its extension is .json and as
+// text otherwise.
+func WriteCoverageFile(fileName string) error {
+	format := CoverageFormatText
+	if filepath.Ext(fileName) == ".json" {
+		format = CoverageFormatJSON
+	}
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	return errors.Join(WriteCoverage(file, format), file.Close())
+}
+
+// oneofTypes returns the type names of the message variants of a Command or Event oneof, as named by
+// eventsourcing.MessageTypeOf, or nil when the type is not a protobuf oneof wrapper.
+func oneofTypes[Message any]() []string {
+	var zero Message
+	msg, ok := any(zero).(proto.Message)
+	if !ok {
+		return nil
+	}
+	oneof, err := protobuf.Oneof(msg)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i < oneof.Fields().Len(); i++ {
+		if field := oneof.Fields().Get(i); field.Message() != nil {
+			names = append(names, string(field.Message().FullName()))
+		}
+	}
+	return names
+}
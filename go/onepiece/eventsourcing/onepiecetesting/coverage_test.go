@@ -0,0 +1,92 @@
+package onepiecetesting_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+	"testing"
+)
+
+var errStructCommand = errors.New("struct command")
+
+func TestCoverage(t *testing.T) {
+	// listDecider turns a list into a struct, and rejects structs.
+	listDecider := onepiece.NewDecider(
+		func(state int, command *structpb.Value) ([]*structpb.Value, error) {
+			if command.GetStructValue() != nil {
+				return nil, errStructCommand
+			}
+			return []*structpb.Value{structpb.NewStructValue(&structpb.Struct{})}, nil
+		},
+		func(state int, event *structpb.Value) int { return state + 1 },
+	)
+	coverage := onepiecetesting.TrackCoverage(listDecider, "list", map[string]error{
+		"errStructCommand": errStructCommand,
+		"errUnused":        errors.New("unused"),
+	})
+	list := structpb.NewListValue(&structpb.ListValue{})
+
+	onepiecetesting.NewTestCase(t, listDecider).
+		When(list).
+		Then(structpb.NewStructValue(&structpb.Struct{})).
+		Assert()
+
+	report := coverage.Report()
+	require.False(t, report.Complete())
+	require.Equal(t, onepiecetesting.CoverageSection{
+		Covered:   []string{"google.protobuf.ListValue"},
+		Uncovered: []string{"google.protobuf.Struct"},
+	}, report.Commands)
+	require.Equal(t, onepiecetesting.CoverageSection{
+		Covered:   []string{"google.protobuf.Struct"},
+		Uncovered: []string{"google.protobuf.ListValue"},
+	}, report.Events)
+	require.Equal(t, []string{"errStructCommand", "errUnused"}, report.Errors.Uncovered)
+
+	onepiecetesting.NewTestCase(t, listDecider).
+		When(structpb.NewStructValue(&structpb.Struct{})).
+		Catch(errStructCommand).
+		Assert()
+
+	report = coverage.Report()
+	require.Empty(t, report.Commands.Uncovered)
+	require.Equal(t, []string{"errStructCommand"}, report.Errors.Covered)
+	require.Equal(t, []string{"errUnused"}, report.Errors.Uncovered)
+	require.Equal(t, "list: commands 2/2, events 1/2, errors 1/2\n"+
+		"  uncovered events: google.protobuf.ListValue\n"+
+		"  uncovered errors: errUnused\n", report.String())
+
+	var output bytes.Buffer
+	require.NoError(t, onepiecetesting.WriteCoverage(&output, onepiecetesting.CoverageFormatJSON))
+	var reports []onepiecetesting.CoverageReport
+	require.NoError(t, json.Unmarshal(output.Bytes(), &reports))
+	require.Contains(t, reports, report)
+}
+
+func TestCoverageTerminalState(t *testing.T) {
+	// closedDecider is terminal once it evolved an event.
+	closedDecider := onepiece.NewDecider(
+		func(state int, command *structpb.Value) ([]*structpb.Value, error) {
+			return []*structpb.Value{command}, nil
+		},
+		func(state int, event *structpb.Value) int { return state + 1 },
+	).WithIsTerminal(func(state int) bool { return state > 0 })
+	coverage := onepiecetesting.TrackCoverage(closedDecider, "closed", map[string]error{
+		"ErrTerminalState": onepiece.ErrTerminalState,
+	})
+
+	onepiecetesting.NewTestCase(t, closedDecider).
+		Given(structpb.NewBoolValue(true)).
+		When(structpb.NewStringValue("a")).
+		Catch(onepiece.ErrTerminalState).
+		Assert()
+
+	report := coverage.Report()
+	require.Equal(t, []string{"google.protobuf.Value"}, report.Commands.Covered)
+	require.Empty(t, report.Events.Covered)
+	require.Equal(t, []string{"ErrTerminalState"}, report.Errors.Covered)
+}
@@ -0,0 +1,278 @@
+package onepiecetesting
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofrs/uuid"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+// StoredEvent is an event appended to a MemoryEventStore.
+type StoredEvent struct {
+	eventsourcing.EventData
+	StreamId string
+	Revision uint64
+}
+
+// DecodedMetadata decodes the JSON metadata of the event, such as $correlationId and $causationId.
+func (e StoredEvent) DecodedMetadata() (eventsourcing.Metadata, error) {
+	metadata := eventsourcing.Metadata{}
+	if len(e.Metadata) == 0 {
+		return metadata, nil
+	}
+	err := json.Unmarshal(e.Metadata, &metadata)
+	return metadata, err
+}
+
+// MemoryEventStore is an EventStore keeping the events in memory, for integration tests of a CommandHandler.
+type MemoryEventStore struct {
+	mu     sync.Mutex
+	events []StoredEvent
+}
+
+func NewMemoryEventStore() *MemoryEventStore {
+	return &MemoryEventStore{}
+}
+
+// Events returns every event appended to the store, across streams, in order.
+func (s *MemoryEventStore) Events() []StoredEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]StoredEvent(nil), s.events...)
+}
+
+// Stream returns the events of the stream in order.
+func (s *MemoryEventStore) Stream(streamId string) []StoredEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stream(streamId)
+}
+
+func (s *MemoryEventStore) ReadStream(_ context.Context, streamId string) ([]eventsourcing.RecordedEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var recorded []eventsourcing.RecordedEvent
+	for _, event := range s.stream(streamId) {
+		recorded = append(recorded, eventsourcing.RecordedEvent{
+			EventId:   event.EventID.String(),
+			EventType: event.EventType,
+			Data:      event.Data,
+			Metadata:  event.Metadata,
+			Revision:  event.Revision,
+		})
+	}
+	return recorded, nil
+}
+
+func (s *MemoryEventStore) AppendToStream(
+	_ context.Context,
+	streamId string,
+	expectedRevision eventsourcing.ExpectedRevision,
+	events []eventsourcing.EventData,
+) (*eventsourcing.WriteResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stream := s.stream(streamId)
+	if !matchesRevision(expectedRevision, stream) {
+		return nil, eventsourcing.ErrOptimisticConcurrency
+	}
+
+	next := uint64(len(stream))
+	for _, event := range events {
+		s.events = append(s.events, StoredEvent{EventData: event, StreamId: streamId, Revision: next})
+		next++
+	}
+
+	result := &eventsourcing.WriteResult{}
+	if next > 0 {
+		result.NextExpectedVersion = next - 1
+	}
+	return result, nil
+}
+
+func (s *MemoryEventStore) stream(streamId string) []StoredEvent {
+	var stream []StoredEvent
+	for _, event := range s.events {
+		if event.StreamId == streamId {
+			stream = append(stream, event)
+		}
+	}
+	return stream
+}
+
+func matchesRevision(expected eventsourcing.ExpectedRevision, stream []StoredEvent) bool {
+	switch r := expected.(type) {
+	case eventsourcing.NoStream:
+		return len(stream) == 0
+	case eventsourcing.StreamExists:
+		return len(stream) > 0
+	case eventsourcing.StreamRevision:
+		return len(stream) > 0 && stream[len(stream)-1].Revision == r.Value
+	default:
+		return true
+	}
+}
+
+// DispatchHarness dispatches commands through a real CommandHandler, such as the one of eventsourcing.NewDecider,
+// backed by a MemoryEventStore, to test the stream ids, event types, codec, metadata and revisions of the handler.
+type DispatchHarness[Command any, Event any] struct {
+	t       *testing.T
+	handler eventsourcing.CommandHandler[Command, Event]
+	codec   Codec[Event]
+	store   *MemoryEventStore
+}
+
+// NewDispatchHarness returns a DispatchHarness with an empty store. The codec marshals the events given to Seed.
+func NewDispatchHarness[Command any, Event any](
+	t *testing.T,
+	handler eventsourcing.CommandHandler[Command, Event],
+	codec Codec[Event],
+) *DispatchHarness[Command, Event] {
+	return &DispatchHarness[Command, Event]{
+		t:       t,
+		handler: handler,
+		codec:   codec,
+		store:   NewMemoryEventStore(),
+	}
+}
+
+func (h *DispatchHarness[Command, Event]) Store() *MemoryEventStore {
+	return h.store
+}
+
+// Seed appends the events to the stream, without metadata.
+func (h *DispatchHarness[Command, Event]) Seed(streamId string, events ...Event) *DispatchHarness[Command, Event] {
+	h.t.Helper()
+
+	eventData := make([]eventsourcing.EventData, len(events))
+	for i, event := range events {
+		eventType, err := h.codec.GetEventType(event)
+		require.NoError(h.t, err, "error getting the event type of a seeded event")
+		contentType, data, err := h.codec.Marshal(event)
+		require.NoError(h.t, err, "error marshaling a seeded event")
+
+		eventData[i] = eventsourcing.EventData{
+			EventID:     uuid.Must(uuid.NewV4()),
+			EventType:   eventType.String(),
+			ContentType: contentType,
+			Data:        data,
+		}
+	}
+
+	_, err := h.store.AppendToStream(context.Background(), streamId, eventsourcing.Any{}, eventData)
+	require.NoError(h.t, err, "error seeding stream %s", streamId)
+	return h
+}
+
+// Dispatch dispatches the command, with optional options, and returns the outcome to assert on.
+func (h *DispatchHarness[Command, Event]) Dispatch(command Command, opts *eventsourcing.Options) *Dispatched[Event] {
+	before := len(h.store.Events())
+	result, err := h.handler(context.Background(), h.store, command, opts)
+
+	return &Dispatched[Event]{
+		t:      h.t,
+		Result: result,
+		Err:    err,
+		Stored: h.store.Events()[before:],
+	}
+}
+
+// Dispatched is the outcome of a dispatched command.
+type Dispatched[Event any] struct {
+	t      *testing.T
+	Result *eventsourcing.Result[Event]
+	Err    error
+	// Stored is the events appended by the command.
+	Stored []StoredEvent
+}
+
+func (d *Dispatched[Event]) AssertNoError() *Dispatched[Event] {
+	d.t.Helper()
+	require.NoError(d.t, d.Err, "unexpected dispatch error")
+	return d
+}
+
+// AssertError asserts that the dispatch failed with the target error, and stored no event.
+func (d *Dispatched[Event]) AssertError(target error) *Dispatched[Event] {
+	d.t.Helper()
+	require.ErrorIs(d.t, d.Err, target)
+	require.Empty(d.t, d.Stored, "events stored by a failed dispatch")
+	return d
+}
+
+// AssertStreamId asserts that every stored event was appended to the stream.
+func (d *Dispatched[Event]) AssertStreamId(streamId string) *Dispatched[Event] {
+	d.t.Helper()
+	for _, event := range d.Stored {
+		require.Equal(d.t, streamId, event.StreamId, "unexpected stream of %s", event.EventType)
+	}
+	return d
+}
+
+// AssertEventTypes asserts the types of the stored events, in order.
+func (d *Dispatched[Event]) AssertEventTypes(eventTypes ...string) *Dispatched[Event] {
+	d.t.Helper()
+	var actual []string
+	for _, event := range d.Stored {
+		actual = append(actual, event.EventType)
+	}
+	require.Equal(d.t, eventTypes, actual, "unexpected stored event types")
+	return d
+}
+
+// AssertContentType asserts the content type of every stored event.
+func (d *Dispatched[Event]) AssertContentType(contentType eventsourcing.ContentType) *Dispatched[Event] {
+	d.t.Helper()
+	for _, event := range d.Stored {
+		require.Equal(d.t, contentType, event.ContentType, "unexpected content type of %s", event.EventType)
+	}
+	return d
+}
+
+// AssertMetadata asserts the value of a key of the decoded metadata of every stored event, the value being compared
+// as decoded from JSON.
+func (d *Dispatched[Event]) AssertMetadata(key string, value any) *Dispatched[Event] {
+	d.t.Helper()
+	for _, event := range d.Stored {
+		metadata, err := event.DecodedMetadata()
+		require.NoError(d.t, err, "error decoding the metadata of %s", event.EventType)
+		require.Equal(d.t, value, metadata[key], "unexpected metadata %s of %s", key, event.EventType)
+	}
+	return d
+}
+
+func (d *Dispatched[Event]) AssertCorrelationId(id eventsourcing.CorrelationId) *Dispatched[Event] {
+	d.t.Helper()
+	return d.AssertMetadata("$correlationId", string(id))
+}
+
+func (d *Dispatched[Event]) AssertCausationId(id eventsourcing.CausationId) *Dispatched[Event] {
+	d.t.Helper()
+	return d.AssertMetadata("$causationId", string(id))
+}
+
+// AssertRevisions asserts that the stored events follow each other in their stream from the first revision.
+func (d *Dispatched[Event]) AssertRevisions(first uint64) *Dispatched[Event] {
+	d.t.Helper()
+	for i, event := range d.Stored {
+		require.Equal(d.t, first+uint64(i), event.Revision, "unexpected revision of %s", event.EventType)
+	}
+	return d
+}
+
+// AssertNextExpectedVersion asserts the NextExpectedVersion of the Result, which must be the revision of the last
+// stored event.
+func (d *Dispatched[Event]) AssertNextExpectedVersion(version uint64) *Dispatched[Event] {
+	d.t.Helper()
+	require.NotNil(d.t, d.Result, "no result")
+	require.Equal(d.t, version, d.Result.NextExpectedVersion, "unexpected next expected version")
+	if len(d.Stored) > 0 {
+		require.Equal(d.t, d.Stored[len(d.Stored)-1].Revision, version, "next expected version is not the last revision")
+	}
+	return d
+}
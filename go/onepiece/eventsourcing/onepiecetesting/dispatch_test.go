@@ -0,0 +1,60 @@
+package onepiecetesting_test
+
+import (
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"testing"
+)
+
+func TestDispatchHarness(t *testing.T) {
+	handler := eventsourcing.NewDecider(
+		counter,
+		func(command string) (string, error) { return "counter-a", nil },
+		stringCodec.Marshal,
+		stringCodec.Unmarshal,
+		stringCodec.GetEventType,
+	)
+	correlationId := eventsourcing.CorrelationId("correlation-1")
+	causationId := eventsourcing.CausationId("causation-1")
+
+	t.Run("stores the decided events after the seeded ones", func(t *testing.T) {
+		onepiecetesting.NewDispatchHarness(t, handler, stringCodec).
+			Seed("counter-a", "incremented").
+			Dispatch("increment", &eventsourcing.Options{
+				CorrelationId: &correlationId,
+				CausationId:   &causationId,
+				Metadata:      eventsourcing.Metadata{"tenant": "acme"},
+			}).
+			AssertNoError().
+			AssertStreamId("counter-a").
+			AssertEventTypes("counter.incremented").
+			AssertContentType(eventsourcing.ContentTypeJson).
+			AssertCorrelationId(correlationId).
+			AssertCausationId(causationId).
+			AssertMetadata("tenant", "acme").
+			AssertRevisions(1).
+			AssertNextExpectedVersion(1)
+	})
+
+	t.Run("stores nothing on a rejected command", func(t *testing.T) {
+		onepiecetesting.NewDispatchHarness(t, handler, stringCodec).
+			Seed("counter-a", "incremented", "incremented").
+			Dispatch("increment", nil).
+			AssertError(errLimitReached)
+	})
+
+	t.Run("stores nothing on a terminal state", func(t *testing.T) {
+		onepiecetesting.NewDispatchHarness(t, handler, stringCodec).
+			Seed("counter-a", "closed").
+			Dispatch("increment", nil).
+			AssertError(onepiece.ErrTerminalState)
+	})
+
+	t.Run("stores nothing on a stale expected revision", func(t *testing.T) {
+		onepiecetesting.NewDispatchHarness(t, handler, stringCodec).
+			Seed("counter-a", "incremented").
+			Dispatch("increment", &eventsourcing.Options{ExpectedRevision: eventsourcing.NoStream{}}).
+			AssertError(eventsourcing.ErrOptimisticConcurrency)
+	})
+}
@@ -0,0 +1,313 @@
+package onepiecetesting
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"html/template"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DocExample is a use case, or a step of a scenario, documenting a command.
+type DocExample struct {
+	Description string
+	// Given is the history the command is decided on, the events of the previous steps of a scenario included.
+	Given     []Message
+	When      Message
+	Then      []Message
+	Exception *Exception
+}
+
+// DocCommand is the documentation of a command type.
+type DocCommand struct {
+	Type     string
+	Examples []DocExample
+}
+
+// DocTransition is a transition observed in the examples: the command moves the stream from the state reached with
+// the From event type to the state reached with the To event type. From is [*] for a new stream.
+type DocTransition struct {
+	From    string
+	To      string
+	Command string
+}
+
+// Docs is the living documentation of a decider, built from its testing files.
+type Docs struct {
+	Title       string
+	Commands    []DocCommand
+	Transitions []DocTransition
+}
+
+// NewDocs documents every command of the testing files with its examples, and the transitions between the states of
+// the stream. The state of a stream is named after its last event, since the testing files do not name states.
+// Skipped use cases are left out.
+func NewDocs(title string, files ...*TestingFile) *Docs {
+	docs := &Docs{Title: title}
+	commands := map[string]*DocCommand{}
+	transitions := map[DocTransition]bool{}
+
+	add := func(example DocExample) {
+		command, ok := commands[example.When.Type]
+		if !ok {
+			command = &DocCommand{Type: example.When.Type}
+			commands[example.When.Type] = command
+		}
+		command.Examples = append(command.Examples, example)
+
+		if example.Exception != nil || len(example.Then) == 0 {
+			return
+		}
+		transition := DocTransition{
+			From:    lastMessageType(example.Given),
+			To:      lastMessageType(example.Then),
+			Command: example.When.Type,
+		}
+		if !transitions[transition] {
+			transitions[transition] = true
+			docs.Transitions = append(docs.Transitions, transition)
+		}
+	}
+
+	for _, file := range files {
+		for _, useCase := range file.UseCases {
+			if useCase.Skip {
+				continue
+			}
+			if len(useCase.Case.Steps) == 0 {
+				add(DocExample{
+					Description: useCase.Description,
+					Given:       useCase.Case.Given,
+					When:        useCase.Case.When,
+					Then:        useCase.Case.Then,
+					Exception:   useCase.Case.Exception,
+				})
+				continue
+			}
+
+			history := append([]Message(nil), useCase.Case.Given...)
+			for i, step := range useCase.Case.Steps {
+				description := step.Description
+				if description == "" {
+					description = fmt.Sprintf("step %d", i+1)
+				}
+				add(DocExample{
+					Description: useCase.Description + ": " + description,
+					Given:       history,
+					When:        step.When,
+					Then:        step.Then,
+					Exception:   step.Exception,
+				})
+				if step.Exception == nil {
+					history = append(history[:len(history):len(history)], step.Then...)
+				}
+			}
+		}
+	}
+
+	for _, command := range commands {
+		docs.Commands = append(docs.Commands, *command)
+	}
+	sort.Slice(docs.Commands, func(i, j int) bool { return docs.Commands[i].Type < docs.Commands[j].Type })
+	return docs
+}
+
+func lastMessageType(messages []Message) string {
+	if len(messages) == 0 {
+		return "[*]"
+	}
+	return messages[len(messages)-1].Type
+}
+
+var mermaidUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// Mermaid returns the state diagram of the transitions, in Mermaid syntax.
+func (d *Docs) Mermaid() string {
+	var builder strings.Builder
+	builder.WriteString("stateDiagram-v2\n")
+	for _, transition := range d.Transitions {
+		fmt.Fprintf(&builder, "    %s --> %s: %s\n",
+			mermaidState(transition.From), mermaidState(transition.To), transition.Command)
+	}
+	return builder.String()
+}
+
+func mermaidState(name string) string {
+	if name == "[*]" {
+		return name
+	}
+	return mermaidUnsafe.ReplaceAllString(name, "_")
+}
+
+// docCell is the content of a Given, When or Then cell.
+type docCell struct {
+	Messages []docMessage
+	Rejected string
+}
+
+type docMessage struct {
+	Type   string
+	Fields []string
+}
+
+func newDocCell(messages []Message, exception *Exception) docCell {
+	cell := docCell{}
+	for _, message := range messages {
+		cell.Messages = append(cell.Messages, docMessage{
+			Type:   message.Type,
+			Fields: payloadFields(&message.Payload, "", nil),
+		})
+	}
+	if exception != nil {
+		cell.Rejected = exception.String()
+	}
+	return cell
+}
+
+// payloadFields flattens a payload into "key: value" lines, nested keys being joined with dots.
+func payloadFields(node *yaml.Node, prefix string, fields []string) []string {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, content := range node.Content {
+			fields = payloadFields(content, prefix, fields)
+		}
+	case yaml.AliasNode:
+		fields = payloadFields(node.Alias, prefix, fields)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			fields = payloadFields(node.Content[i+1], key, fields)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			fields = payloadFields(item, fmt.Sprintf("%s[%d]", prefix, i), fields)
+		}
+	case yaml.ScalarNode:
+		if prefix == "" {
+			return append(fields, node.Value)
+		}
+		fields = append(fields, prefix+": "+node.Value)
+	}
+	return fields
+}
+
+// WriteMarkdown writes the documentation as Markdown, with a table of examples per command.
+func (d *Docs) WriteMarkdown(w io.Writer) error {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "# %s\n\n", d.Title)
+	fmt.Fprintf(&builder, "```mermaid\n%s```\n", d.Mermaid())
+
+	for _, command := range d.Commands {
+		fmt.Fprintf(&builder, "\n## %s\n\n", command.Type)
+		builder.WriteString("| Example | Given | When | Then |\n")
+		builder.WriteString("| --- | --- | --- | --- |\n")
+		for _, example := range command.Examples {
+			fmt.Fprintf(&builder, "| %s | %s | %s | %s |\n",
+				markdownText(example.Description),
+				markdownCell(newDocCell(example.Given, nil), "_new stream_"),
+				markdownCell(newDocCell([]Message{example.When}, nil), ""),
+				markdownCell(newDocCell(example.Then, example.Exception), "_no events_"),
+			)
+		}
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+func markdownCell(cell docCell, empty string) string {
+	var lines []string
+	for _, message := range cell.Messages {
+		lines = append(lines, "**"+markdownText(message.Type)+"**")
+		for _, field := range message.Fields {
+			lines = append(lines, markdownText(field))
+		}
+	}
+	if cell.Rejected != "" {
+		lines = append(lines, "**Rejected** with "+markdownText(cell.Rejected))
+	}
+	if len(lines) == 0 {
+		return empty
+	}
+	return strings.Join(lines, "<br>")
+}
+
+// markdownEscaper escapes the text of a table cell, which may hold HTML line breaks.
+var markdownEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "|", `\|`)
+
+func markdownText(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+var docsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2rem; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2rem; }
+th, td { border: 1px solid #ccc; padding: 0.5rem; text-align: left; vertical-align: top; }
+.field { color: #555; font-size: 0.9em; }
+.rejected { color: #b00020; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<pre class="mermaid">
+{{.Mermaid}}</pre>
+{{- range .Commands}}
+<h2>{{.Type}}</h2>
+<table>
+<tr><th>Example</th><th>Given</th><th>When</th><th>Then</th></tr>
+{{- range .Examples}}
+<tr><td>{{.Description}}</td><td>{{template "cell" .Given}}</td><td>{{template "cell" .When}}</td><td>{{template "cell" .Then}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<script type="module">
+import mermaid from "https://cdn.jsdelivr.net/npm/mermaid@10/dist/mermaid.esm.min.mjs";
+mermaid.initialize({ startOnLoad: true });
+</script>
+</body>
+</html>
+{{define "cell"}}{{range .Messages}}<strong>{{.Type}}</strong>{{range .Fields}}<br><span class="field">{{.}}</span>{{end}}<br>{{end}}{{if .Rejected}}<span class="rejected">Rejected with {{.Rejected}}</span>{{end}}{{end}}`))
+
+// WriteHTML writes the documentation as a standalone HTML page, rendering the state diagram with Mermaid.
+func (d *Docs) WriteHTML(w io.Writer) error {
+	type htmlExample struct {
+		Description string
+		Given       docCell
+		When        docCell
+		Then        docCell
+	}
+	type htmlCommand struct {
+		Type     string
+		Examples []htmlExample
+	}
+
+	var commands []htmlCommand
+	for _, command := range d.Commands {
+		htmlCommand := htmlCommand{Type: command.Type}
+		for _, example := range command.Examples {
+			htmlCommand.Examples = append(htmlCommand.Examples, htmlExample{
+				Description: example.Description,
+				Given:       newDocCell(example.Given, nil),
+				When:        newDocCell([]Message{example.When}, nil),
+				Then:        newDocCell(example.Then, example.Exception),
+			})
+		}
+		commands = append(commands, htmlCommand)
+	}
+
+	return docsTemplate.Execute(w, struct {
+		Title    string
+		Mermaid  string
+		Commands []htmlCommand
+	}{Title: d.Title, Mermaid: d.Mermaid(), Commands: commands})
+}
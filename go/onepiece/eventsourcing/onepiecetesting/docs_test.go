@@ -0,0 +1,84 @@
+package onepiecetesting_test
+
+import (
+	"bytes"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+const docsSpec = `
+useCases:
+  - description: opens the account
+    case:
+      when:
+        type: Open
+        payload:
+          owner: {name: Ada}
+      then:
+        - type: Opened
+          payload:
+            owner: {name: Ada}
+  - description: closes the account
+    case:
+      steps:
+        - description: closes it
+          when:
+            type: Close
+          then:
+            - type: Closed
+        - description: rejects closing it twice
+          when:
+            type: Close
+          exception: ErrClosed
+      given:
+        - type: Opened
+  - description: skipped
+    skip: true
+    case:
+      when:
+        type: Reopen
+`
+
+func TestDocs(t *testing.T) {
+	var spec onepiecetesting.TestingFile
+	require.NoError(t, yaml.Unmarshal([]byte(docsSpec), &spec))
+	docs := onepiecetesting.NewDocs("Account", &spec)
+
+	require.Equal(t, []onepiecetesting.DocTransition{
+		{From: "[*]", To: "Opened", Command: "Open"},
+		{From: "Opened", To: "Closed", Command: "Close"},
+	}, docs.Transitions)
+
+	var markdown bytes.Buffer
+	require.NoError(t, docs.WriteMarkdown(&markdown))
+	require.Equal(t, "# Account\n"+
+		"\n"+
+		"```mermaid\n"+
+		"stateDiagram-v2\n"+
+		"    [*] --> Opened: Open\n"+
+		"    Opened --> Closed: Close\n"+
+		"```\n"+
+		"\n"+
+		"## Close\n"+
+		"\n"+
+		"| Example | Given | When | Then |\n"+
+		"| --- | --- | --- | --- |\n"+
+		"| closes the account: closes it | **Opened** | **Close** | **Closed** |\n"+
+		"| closes the account: rejects closing it twice | **Opened**<br>**Closed** | **Close** | **Rejected** with name ErrClosed |\n"+
+		"\n"+
+		"## Open\n"+
+		"\n"+
+		"| Example | Given | When | Then |\n"+
+		"| --- | --- | --- | --- |\n"+
+		"| opens the account | _new stream_ | **Open**<br>owner.name: Ada | **Opened**<br>owner.name: Ada |\n",
+		markdown.String())
+
+	var page bytes.Buffer
+	require.NoError(t, docs.WriteHTML(&page))
+	require.Contains(t, page.String(), `<pre class="mermaid">`)
+	require.Contains(t, page.String(), "<h2>Close</h2>")
+	require.Contains(t, page.String(), `<span class="rejected">Rejected with name ErrClosed</span>`)
+	require.NotContains(t, page.String(), "Reopen")
+}
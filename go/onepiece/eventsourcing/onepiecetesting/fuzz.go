@@ -0,0 +1,177 @@
+package onepiecetesting
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"google.golang.org/protobuf/testing/protocmp"
+	"math/rand"
+	"testing"
+)
+
+const (
+	defaultFuzzMaxEvents   = 10
+	defaultFuzzMaxCommands = 5
+	fuzzSeedCorpusSize     = 8
+	fuzzSeedLength         = 64
+)
+
+var ErrCodecAsymmetry = errors.New("event changed by a marshal and unmarshal round-trip")
+
+// fuzzSource is a rand.Source reading its values from the fuzz input, so the fuzzer mutates the generated values
+// rather than a seed. Once the input is consumed, every value is zero.
+type fuzzSource struct {
+	data []byte
+}
+
+func (s *fuzzSource) Uint64() uint64 {
+	var b [8]byte
+	n := copy(b[:], s.data)
+	s.data = s.data[n:]
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+func (s *fuzzSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+func (s *fuzzSource) Seed(int64) {}
+
+// FuzzRand returns a rand.Rand drawing its values from the fuzz input, to use the Generators in fuzz targets.
+func FuzzRand(data []byte) *rand.Rand {
+	return rand.New(&fuzzSource{data: data})
+}
+
+// FuzzSequence draws up to max values from the generator, the number of values being drawn from the source too.
+func FuzzSequence[T any](source *rand.Rand, generate Generator[T], max int) []T {
+	values := make([]T, source.Intn(max+1))
+	for i := range values {
+		values[i] = generate(source)
+	}
+	return values
+}
+
+// Codec is the wiring of the events of a decider, see protobuf.Validate.
+type Codec[Event any] struct {
+	Marshal      eventsourcing.MarshalEvent[Event]
+	Unmarshal    eventsourcing.UnmarshalEvent[Event]
+	GetEventType eventsourcing.GetEventType[Event]
+}
+
+// RoundTrip marshals and unmarshals the event with its event type, and returns an error wrapping ErrCodecAsymmetry
+// when the unmarshaled event differs from the original one.
+func (c Codec[Event]) RoundTrip(event Event) error {
+	eventType, err := c.GetEventType(event)
+	if err != nil {
+		return fmt.Errorf("event type of %s: %w", formatMessage(event), err)
+	}
+	_, data, err := c.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", eventType, err)
+	}
+	decoded, err := c.Unmarshal(eventType.String(), data)
+	if err != nil {
+		return fmt.Errorf("unmarshal %s: %w", eventType, err)
+	}
+	if diff := cmp.Diff(event, decoded, protocmp.Transform()); diff != "" {
+		return fmt.Errorf("%w: %s (-want +got):\n%s", ErrCodecAsymmetry, eventType, diff)
+	}
+	return nil
+}
+
+type FuzzConfig[Command any, Event any] struct {
+	// Commands generates the commands decided by every input.
+	Commands Generator[Command]
+	// Events generates the history the commands are decided on. No history is generated when nil.
+	Events Generator[Event]
+	// Codec, when set, round-trips the history and every decided event.
+	Codec *Codec[Event]
+	// MaxEvents is the maximum length of the history. Defaults to 10.
+	MaxEvents int
+	// MaxCommands is the maximum number of commands decided by every input. Defaults to 5.
+	MaxCommands int
+}
+
+// FuzzDecider fuzzes the decider with an event history followed by a sequence of commands, both drawn from the fuzz
+// input: the history is evolved, then every command is decided and its events evolved, like eventsourcing.NewDecider
+// does. Panics, such as nil dereferences on the unset fields of generated messages, fail the input. Call it from a
+// FuzzXxx function, and run it with go test -fuzz.
+func FuzzDecider[State any, Command any, Event any](
+	f *testing.F,
+	decider *onepiece.Decider[State, Command, Event],
+	config FuzzConfig[Command, Event],
+) {
+	if config.Commands == nil {
+		f.Fatal("fuzzing a decider requires a command generator")
+	}
+	if config.MaxEvents <= 0 {
+		config.MaxEvents = defaultFuzzMaxEvents
+	}
+	if config.MaxCommands <= 0 {
+		config.MaxCommands = defaultFuzzMaxCommands
+	}
+
+	addSeedCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		source := FuzzRand(data)
+
+		var history []Event
+		if config.Events != nil {
+			history = FuzzSequence(source, config.Events, config.MaxEvents)
+		}
+		commands := FuzzSequence(source, config.Commands, config.MaxCommands)
+
+		state := decider.InitialState()
+		for _, event := range history {
+			roundTrip(t, config.Codec, event)
+			state = decider.Evolve(state, event)
+		}
+
+		for _, command := range commands {
+			if decider.IsTerminal(state) {
+				return
+			}
+			events, err := decider.Decide(state, command)
+			if err != nil {
+				continue
+			}
+			for _, event := range events {
+				roundTrip(t, config.Codec, event)
+				state = decider.Evolve(state, event)
+			}
+		}
+	})
+}
+
+// FuzzCodec fuzzes the round-trip of the events drawn from the fuzz input, see Codec.RoundTrip.
+func FuzzCodec[Event any](f *testing.F, events Generator[Event], codec Codec[Event]) {
+	addSeedCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		roundTrip(t, &codec, events(FuzzRand(data)))
+	})
+}
+
+func roundTrip[Event any](t *testing.T, codec *Codec[Event], event Event) {
+	t.Helper()
+
+	if codec == nil {
+		return
+	}
+	if err := codec.RoundTrip(event); err != nil {
+		t.Fatalf("%v\nevent: %s", err, formatMessage(event))
+	}
+}
+
+// addSeedCorpus adds an empty input and a few random ones, so the targets run as plain tests without -fuzz.
+func addSeedCorpus(f *testing.F) {
+	source := rand.New(rand.NewSource(1))
+	f.Add([]byte{})
+	for i := 0; i < fuzzSeedCorpusSize; i++ {
+		data := make([]byte, fuzzSeedLength)
+		source.Read(data)
+		f.Add(data)
+	}
+}
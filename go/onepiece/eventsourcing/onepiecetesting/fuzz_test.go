@@ -0,0 +1,59 @@
+package onepiecetesting_test
+
+import (
+	"errors"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecemessage"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+var stringCodec = onepiecetesting.Codec[string]{
+	Marshal: func(event string) (eventsourcing.ContentType, []byte, error) {
+		return eventsourcing.ContentTypeJson, []byte(event), nil
+	},
+	Unmarshal: func(eventType string, data []byte) (string, error) {
+		return string(data), nil
+	},
+	GetEventType: func(event string) (*onepiecemessage.MessageType, error) {
+		eventType := onepiecemessage.MessageType("counter." + event)
+		return &eventType, nil
+	},
+}
+
+func FuzzCounter(f *testing.F) {
+	onepiecetesting.FuzzDecider(f, counter, onepiecetesting.FuzzConfig[string, string]{
+		Commands: onepiecetesting.OneOf("increment", "close"),
+		Events:   onepiecetesting.OneOf("incremented"),
+		Codec:    &stringCodec,
+	})
+}
+
+func TestFuzzSequence(t *testing.T) {
+	data := []byte{3, 0, 0, 0, 0, 0, 0, 0, 1}
+	generate := onepiecetesting.OneOf("a", "b")
+
+	sequence := onepiecetesting.FuzzSequence(onepiecetesting.FuzzRand(data), generate, 5)
+	require.Equal(t, sequence, onepiecetesting.FuzzSequence(onepiecetesting.FuzzRand(data), generate, 5))
+	require.LessOrEqual(t, len(sequence), 5)
+
+	require.Empty(t, onepiecetesting.FuzzSequence(onepiecetesting.FuzzRand(nil), generate, 5))
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	require.NoError(t, stringCodec.RoundTrip("incremented"))
+
+	lossy := stringCodec
+	lossy.Unmarshal = func(eventType string, data []byte) (string, error) {
+		return strings.ToUpper(string(data)), nil
+	}
+	require.ErrorIs(t, lossy.RoundTrip("incremented"), onepiecetesting.ErrCodecAsymmetry)
+
+	failing := stringCodec
+	failing.Marshal = func(event string) (eventsourcing.ContentType, []byte, error) {
+		return eventsourcing.ContentTypeJson, nil, errors.New("boom")
+	}
+	require.ErrorContains(t, failing.RoundTrip("incremented"), "marshal counter.incremented: boom")
+}
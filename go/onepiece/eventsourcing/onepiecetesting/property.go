@@ -0,0 +1,244 @@
+package onepiecetesting
+
+import (
+	"errors"
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	defaultPropertyRuns        = 100
+	defaultPropertyMaxCommands = 20
+)
+
+// Generator returns a random value drawn from the source.
+type Generator[T any] func(rand *rand.Rand) T
+
+// OneOf returns a Generator picking one of the values.
+func OneOf[T any](values ...T) Generator[T] {
+	return func(rand *rand.Rand) T {
+		return values[rand.Intn(len(values))]
+	}
+}
+
+// Transition is a command decided by a property check, with the state before and after it.
+type Transition[State any, Command any, Event any] struct {
+	// History is the events decided before the command.
+	History []Event
+	Before  State
+	Command Command
+	Events  []Event
+	Err     error
+	After   State
+}
+
+// Invariant is a property every Transition must hold, e.g. "a drained plan is always archived".
+type Invariant[State any, Command any, Event any] struct {
+	Name string
+	// Check returns an error describing the violation of the invariant.
+	Check func(transition Transition[State, Command, Event]) error
+}
+
+type PropertyConfig[Command any] struct {
+	// Commands generates the commands of the sequences.
+	Commands Generator[Command]
+	// Runs is the number of sequences to check. Defaults to 100.
+	Runs int
+	// MaxCommands is the maximum length of a sequence. Defaults to 20.
+	MaxCommands int
+	// Seed makes the sequences reproducible. Defaults to the current time, and is reported on failure.
+	Seed int64
+}
+
+// Counterexample is the shortest sequence of commands found that breaks an invariant.
+type Counterexample[Command any] struct {
+	Invariant string
+	Message   string
+	Commands  []Command
+	// FailedStep is the index of the command that broke the invariant, the last one once shrunk.
+	FailedStep int
+	Seed       int64
+	Runs       int
+}
+
+func (c *Counterexample[Command]) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "invariant %q broken after %d runs (seed %d): %s\ncommands:\n", c.Invariant, c.Runs, c.Seed, c.Message)
+	for i, command := range c.Commands {
+		marker := " "
+		if i == c.FailedStep {
+			marker = ">"
+		}
+		fmt.Fprintf(&builder, "%s %d. %s\n", marker, i+1, formatMessage(command))
+	}
+	return builder.String()
+}
+
+// CheckProperties fails the test with the Counterexample found by FindCounterexample, if any.
+func CheckProperties[State any, Command any, Event any](
+	t *testing.T,
+	decider *onepiece.Decider[State, Command, Event],
+	config PropertyConfig[Command],
+	invariants ...Invariant[State, Command, Event],
+) {
+	t.Helper()
+
+	counterexample, err := FindCounterexample(decider, config, invariants...)
+	require.NoError(t, err, "invalid property check")
+	if counterexample != nil {
+		t.Fatal(counterexample.String())
+	}
+}
+
+// FindCounterexample decides random sequences of commands, evolving the state with the decided events, and returns
+// the shortest sequence found that breaks an invariant, or nil. Rejected commands decide no events, so the sequences
+// keep going like they would in production. Unlike eventsourcing.NewDecider, commands are decided on terminal states
+// too, so invariants can check that no event follows a terminal state. A panic breaks the "no panics" invariant.
+func FindCounterexample[State any, Command any, Event any](
+	decider *onepiece.Decider[State, Command, Event],
+	config PropertyConfig[Command],
+	invariants ...Invariant[State, Command, Event],
+) (*Counterexample[Command], error) {
+	if config.Commands == nil {
+		return nil, errors.New("property check requires a command generator")
+	}
+	if config.Runs <= 0 {
+		config.Runs = defaultPropertyRuns
+	}
+	if config.MaxCommands <= 0 {
+		config.MaxCommands = defaultPropertyMaxCommands
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+
+	source := rand.New(rand.NewSource(config.Seed))
+	for run := 0; run < config.Runs; run++ {
+		commands := make([]Command, 1+source.Intn(config.MaxCommands))
+		for i := range commands {
+			commands[i] = config.Commands(source)
+		}
+
+		violation := checkSequence(decider, commands, invariants)
+		if violation == nil {
+			continue
+		}
+
+		commands, violation = shrinkSequence(decider, commands, invariants, violation)
+		return &Counterexample[Command]{
+			Invariant:  violation.invariant,
+			Message:    violation.message,
+			Commands:   commands,
+			FailedStep: violation.step,
+			Seed:       config.Seed,
+			Runs:       run + 1,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+type propertyViolation struct {
+	invariant string
+	message   string
+	step      int
+}
+
+// checkSequence decides the commands in order and returns the first broken invariant, or nil.
+func checkSequence[State any, Command any, Event any](
+	decider *onepiece.Decider[State, Command, Event],
+	commands []Command,
+	invariants []Invariant[State, Command, Event],
+) *propertyViolation {
+	state := decider.InitialState()
+	var history []Event
+
+	for step, command := range commands {
+		transition := Transition[State, Command, Event]{
+			History: history,
+			Before:  state,
+			Command: command,
+		}
+
+		if panicked := decideSafely(decider, &transition); panicked != nil {
+			return &propertyViolation{invariant: "no panics", message: fmt.Sprint(panicked), step: step}
+		}
+		if transition.Err != nil {
+			transition.Events = nil
+		}
+
+		transition.After = transition.Before
+		for _, event := range transition.Events {
+			transition.After = decider.Evolve(transition.After, event)
+		}
+
+		for _, invariant := range invariants {
+			if err := invariant.Check(transition); err != nil {
+				return &propertyViolation{invariant: invariant.Name, message: err.Error(), step: step}
+			}
+		}
+
+		state = transition.After
+		history = append(history[:len(history):len(history)], transition.Events...)
+	}
+
+	return nil
+}
+
+func decideSafely[State any, Command any, Event any](
+	decider *onepiece.Decider[State, Command, Event],
+	transition *Transition[State, Command, Event],
+) (panicked any) {
+	defer func() { panicked = recover() }()
+	transition.Events, transition.Err = decider.Decide(transition.Before, transition.Command)
+	return nil
+}
+
+// shrinkSequence removes commands from a failing sequence, from large chunks down to single commands, as long as the
+// sequence keeps breaking the same invariant.
+func shrinkSequence[State any, Command any, Event any](
+	decider *onepiece.Decider[State, Command, Event],
+	commands []Command,
+	invariants []Invariant[State, Command, Event],
+	violation *propertyViolation,
+) ([]Command, *propertyViolation) {
+	// The commands after the failing step do not matter.
+	commands = commands[:violation.step+1]
+
+	for size := len(commands) / 2; size >= 1; {
+		shrunk := false
+		for start := 0; start+size <= len(commands); start++ {
+			candidate := append(append([]Command(nil), commands[:start]...), commands[start+size:]...)
+			if len(candidate) == 0 {
+				continue
+			}
+
+			candidateViolation := checkSequence(decider, candidate, invariants)
+			if candidateViolation != nil && candidateViolation.invariant == violation.invariant {
+				commands = candidate[:candidateViolation.step+1]
+				violation = candidateViolation
+				shrunk = true
+				break
+			}
+		}
+		if !shrunk {
+			size /= 2
+		}
+	}
+
+	return commands, violation
+}
+
+func formatMessage(message any) string {
+	if msg, ok := message.(proto.Message); ok {
+		return fmt.Sprintf("%s{%s}", msg.ProtoReflect().Descriptor().Name(), prototext.MarshalOptions{}.Format(msg))
+	}
+	return fmt.Sprintf("%+v", message)
+}
@@ -0,0 +1,130 @@
+package onepiecetesting_test
+
+import (
+	"errors"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+	"math/rand"
+	"testing"
+)
+
+func TestFindCounterexample(t *testing.T) {
+	increments := onepiecetesting.PropertyConfig[string]{
+		Commands: onepiecetesting.OneOf("increment"),
+		Seed:     1,
+	}
+	atMost := func(limit int) onepiecetesting.Invariant[int, string, string] {
+		return onepiecetesting.Invariant[int, string, string]{
+			Name: "counter within the limit",
+			Check: func(transition onepiecetesting.Transition[int, string, string]) error {
+				if transition.After > limit {
+					return errors.New("counter past the limit")
+				}
+				return nil
+			},
+		}
+	}
+
+	t.Run("holds invariants", func(t *testing.T) {
+		onepiecetesting.CheckProperties(t, counter, increments, atMost(2))
+	})
+
+	t.Run("shrinks the sequence breaking an invariant", func(t *testing.T) {
+		counterexample, err := onepiecetesting.FindCounterexample(counter, increments, atMost(1))
+		require.NoError(t, err)
+		require.NotNil(t, counterexample)
+		require.Equal(t, "counter within the limit", counterexample.Invariant)
+		require.Equal(t, []string{"increment", "increment"}, counterexample.Commands)
+		require.Equal(t, 1, counterexample.FailedStep)
+		require.Contains(t, counterexample.String(), "> 2. increment")
+	})
+
+	t.Run("reports panics", func(t *testing.T) {
+		panicking := onepiece.NewDecider(
+			func(state int, command string) ([]string, error) {
+				if state > 0 {
+					panic("boom")
+				}
+				return []string{"incremented"}, nil
+			},
+			func(state int, event string) int { return state + 1 },
+		)
+
+		counterexample, err := onepiecetesting.FindCounterexample(panicking, increments)
+		require.NoError(t, err)
+		require.Equal(t, "no panics", counterexample.Invariant)
+		require.Equal(t, "boom", counterexample.Message)
+		require.Len(t, counterexample.Commands, 2)
+	})
+
+	t.Run("finds events decided on a terminal state", func(t *testing.T) {
+		closable := func(guarded bool) *onepiece.Decider[int, string, string] {
+			return onepiece.NewDecider(
+				func(state int, command string) ([]string, error) {
+					if guarded && state < 0 {
+						return nil, onepiece.ErrTerminalState
+					}
+					if command == "close" {
+						return []string{"closed"}, nil
+					}
+					return []string{"incremented"}, nil
+				},
+				counter.Evolve,
+			).WithIsTerminal(func(state int) bool { return state < 0 })
+		}
+		config := onepiecetesting.PropertyConfig[string]{
+			Commands: onepiecetesting.OneOf("increment", "close"),
+			Seed:     1,
+		}
+		noEventsAfterTerminal := func(decider *onepiece.Decider[int, string, string]) onepiecetesting.Invariant[int, string, string] {
+			return onepiecetesting.Invariant[int, string, string]{
+				Name: "no events after terminal state",
+				Check: func(transition onepiecetesting.Transition[int, string, string]) error {
+					if decider.IsTerminal(transition.Before) && len(transition.Events) > 0 {
+						return errors.New("events decided on a terminal state")
+					}
+					return nil
+				},
+			}
+		}
+
+		guarded := closable(true)
+		onepiecetesting.CheckProperties(t, guarded, config, noEventsAfterTerminal(guarded))
+
+		unguarded := closable(false)
+		counterexample, err := onepiecetesting.FindCounterexample(unguarded, config, noEventsAfterTerminal(unguarded))
+		require.NoError(t, err)
+		require.NotNil(t, counterexample)
+		require.Equal(t, "no events after terminal state", counterexample.Invariant)
+		require.Len(t, counterexample.Commands, 2)
+		require.Equal(t, "close", counterexample.Commands[0])
+		require.Equal(t, 1, counterexample.FailedStep)
+	})
+
+	t.Run("requires a command generator", func(t *testing.T) {
+		_, err := onepiecetesting.FindCounterexample(counter, onepiecetesting.PropertyConfig[string]{})
+		require.Error(t, err)
+	})
+}
+
+func TestProtoGenerator(t *testing.T) {
+	generate, err := onepiecetesting.ProtoGenerator[*structpb.Value](onepiecetesting.ProtoGeneratorConfig{})
+	require.NoError(t, err)
+
+	source := rand.New(rand.NewSource(1))
+	kinds := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		value := generate(source)
+		switch value.GetKind().(type) {
+		case *structpb.Value_StructValue:
+			kinds["struct"] = true
+		case *structpb.Value_ListValue:
+			kinds["list"] = true
+		default:
+			require.Failf(t, "unexpected variant", "%v", value)
+		}
+	}
+	require.Equal(t, map[string]bool{"struct": true, "list": true}, kinds)
+}
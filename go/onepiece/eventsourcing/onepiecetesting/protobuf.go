@@ -0,0 +1,55 @@
+package onepiecetesting
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/protobuf"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+// UnmarshalProtoMessage returns an UnmarshalMessage that decodes the payloads with protojson into the variant of the
+// oneof named by the message type, either its short name (CreatePlan) or its full name, and wraps it into the Message.
+// Payloads follow the protojson mapping, e.g. timestamps are RFC 3339 strings.
+func UnmarshalProtoMessage[Message proto.Message](registry *protobuf.OneofRegistry[Message]) UnmarshalMessage[Message] {
+	return func(messageType string, payload yaml.Node) (Message, error) {
+		data, err := payloadJSON(payload)
+		if err != nil {
+			var msg Message
+			return msg, fmt.Errorf("invalid payload of %s: %w", messageType, err)
+		}
+		return registry.DecodeJSON(messageType, data)
+	}
+}
+
+// RunProtoTestingFile runs a testing file of a decider of protobuf Command and Event oneofs, decoding the messages
+// with UnmarshalProtoMessage.
+func RunProtoTestingFile[State any, Command proto.Message, Event proto.Message](
+	t *testing.T,
+	fileName string,
+	decider *onepiece.Decider[State, Command, Event],
+	options ...RunOption,
+) {
+	commands, err := protobuf.NewOneofRegistry[Command]()
+	require.NoError(t, err, "invalid command oneof")
+	events, err := protobuf.NewOneofRegistry[Event]()
+	require.NoError(t, err, "invalid event oneof")
+
+	RunTestingFile(t, fileName, decider, UnmarshalProtoMessage(commands), UnmarshalProtoMessage(events), options...)
+}
+
+// payloadJSON converts a YAML payload to JSON, an empty object when the payload is missing.
+func payloadJSON(payload yaml.Node) ([]byte, error) {
+	if payload.IsZero() {
+		return []byte("{}"), nil
+	}
+
+	var value any
+	if err := payload.Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
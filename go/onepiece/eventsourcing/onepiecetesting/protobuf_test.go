@@ -0,0 +1,50 @@
+package onepiecetesting_test
+
+import (
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/straw-hat-team/onepiece/go/onepiece/protobuf"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+func TestUnmarshalProtoMessage(t *testing.T) {
+	unmarshal := onepiecetesting.UnmarshalProtoMessage(protobuf.MustNewOneofRegistry[*structpb.Value]())
+
+	payload := func(t *testing.T, data string) yaml.Node {
+		var node yaml.Node
+		require.NoError(t, yaml.Unmarshal([]byte(data), &node))
+		return *node.Content[0]
+	}
+
+	t.Run("decodes YAML payloads with protojson", func(t *testing.T) {
+		value, err := unmarshal("Struct", payload(t, "title: Vacation\ngoal: 1000\n"))
+		require.NoError(t, err)
+
+		want := structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{
+			"title": structpb.NewStringValue("Vacation"),
+			"goal":  structpb.NewNumberValue(1000),
+		}})
+		require.True(t, proto.Equal(want, value))
+	})
+
+	t.Run("decodes JSON payloads by full name", func(t *testing.T) {
+		value, err := unmarshal("google.protobuf.ListValue", payload(t, `[1, "two"]`))
+		require.NoError(t, err)
+		require.Len(t, value.GetListValue().GetValues(), 2)
+	})
+
+	t.Run("decodes missing payloads as empty messages", func(t *testing.T) {
+		value, err := unmarshal("Struct", yaml.Node{})
+		require.NoError(t, err)
+		require.True(t, proto.Equal(structpb.NewStructValue(&structpb.Struct{}), value))
+	})
+
+	t.Run("rejects unknown message types", func(t *testing.T) {
+		_, err := unmarshal("Unknown", yaml.Node{})
+		require.ErrorIs(t, err, onepiece.ErrUnknownMessage)
+	})
+}
@@ -0,0 +1,147 @@
+package onepiecetesting
+
+import (
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece/protobuf"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"math/rand"
+)
+
+const (
+	defaultProtoGeneratorMaxDepth = 3
+	// maxTimestampSeconds is 9999-12-31T23:59:59Z, the last timestamp protojson accepts.
+	maxTimestampSeconds = 253402300799
+	// maxDurationSeconds is about 10,000 years, the longest duration protojson accepts.
+	maxDurationSeconds = 315576000000
+)
+
+var defaultProtoGeneratorStrings = []string{"", "a", "b"}
+
+type ProtoGeneratorConfig struct {
+	// Strings are the candidate values of the string fields by field name, e.g. {"planId": {"p1", "p2"}}, so the
+	// commands of a sequence hit the same entities. Other string fields take one of DefaultStrings.
+	Strings map[protoreflect.Name][]string
+	// DefaultStrings defaults to "", "a" and "b".
+	DefaultStrings []string
+	// MaxDepth limits the nesting of the generated messages. Defaults to 3.
+	MaxDepth int
+}
+
+// ProtoGenerator returns a Generator of Command or Event oneof wrappers holding a random message variant, with random
+// values in the fields of the variant. Message fields are left unset now and then, to exercise nil handling.
+func ProtoGenerator[Message proto.Message](config ProtoGeneratorConfig) (Generator[Message], error) {
+	var msg Message
+	oneof, err := protobuf.Oneof(msg)
+	if err != nil {
+		return nil, err
+	}
+	var variants []Message
+	for i := 0; i < oneof.Fields().Len(); i++ {
+		if field := oneof.Fields().Get(i); field.Message() != nil {
+			variant, err := protobuf.NewOneofVariant(msg, field)
+			if err != nil {
+				return nil, err
+			}
+			variants = append(variants, variant)
+		}
+	}
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("%w: %s", protobuf.ErrOneofNotMessage, oneof.FullName())
+	}
+	if len(config.DefaultStrings) == 0 {
+		config.DefaultStrings = defaultProtoGeneratorStrings
+	}
+	if config.MaxDepth <= 0 {
+		config.MaxDepth = defaultProtoGeneratorMaxDepth
+	}
+
+	return func(rand *rand.Rand) Message {
+		wrapper := proto.Clone(variants[rand.Intn(len(variants))]).(Message)
+		variant, err := protobuf.OneofValue(wrapper)
+		if err != nil {
+			panic(err)
+		}
+		fillMessage(rand, config, variant.ProtoReflect(), 1)
+		return wrapper
+	}, nil
+}
+
+func fillMessage(rand *rand.Rand, config ProtoGeneratorConfig, msg protoreflect.Message, depth int) {
+	fields := msg.Descriptor().Fields()
+	// Well-known types with a restricted range, so the generated values can be marshaled.
+	switch msg.Descriptor().FullName() {
+	case "google.protobuf.Timestamp":
+		msg.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(rand.Int63n(maxTimestampSeconds)))
+		msg.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(rand.Int31n(1e9)))
+		return
+	case "google.protobuf.Duration":
+		msg.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(rand.Int63n(maxDurationSeconds)))
+		msg.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(rand.Int31n(1e9)))
+		return
+	}
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.IsMap() || (field.ContainingOneof() != nil && !field.ContainingOneof().IsSynthetic()) {
+			continue
+		}
+
+		switch {
+		case field.IsList():
+			list := msg.Mutable(field).List()
+			for n := rand.Intn(3); n > 0; n-- {
+				if field.Message() != nil {
+					if depth >= config.MaxDepth {
+						break
+					}
+					element := list.NewElement()
+					fillMessage(rand, config, element.Message(), depth+1)
+					list.Append(element)
+				} else {
+					list.Append(randomScalar(rand, config, field))
+				}
+			}
+		case field.Message() != nil:
+			if depth >= config.MaxDepth || rand.Intn(5) == 0 {
+				continue
+			}
+			fillMessage(rand, config, msg.Mutable(field).Message(), depth+1)
+		default:
+			msg.Set(field, randomScalar(rand, config, field))
+		}
+	}
+}
+
+func randomScalar(rand *rand.Rand, config ProtoGeneratorConfig, field protoreflect.FieldDescriptor) protoreflect.Value {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(rand.Intn(2) == 0)
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		return protoreflect.ValueOfEnum(values.Get(rand.Intn(values.Len())).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(rand.Intn(2001) - 1000))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(int64(rand.Intn(2001) - 1000))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(rand.Intn(1001)))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(uint64(rand.Intn(1001)))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(rand.Float32() * 1000)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(rand.Float64() * 1000)
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(randomString(rand, config, field)))
+	default:
+		return protoreflect.ValueOfString(randomString(rand, config, field))
+	}
+}
+
+func randomString(rand *rand.Rand, config ProtoGeneratorConfig, field protoreflect.FieldDescriptor) string {
+	values, ok := config.Strings[field.Name()]
+	if !ok || len(values) == 0 {
+		values = config.DefaultStrings
+	}
+	return values[rand.Intn(len(values))]
+}
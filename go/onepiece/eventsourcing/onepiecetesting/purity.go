@@ -0,0 +1,367 @@
+package onepiecetesting
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+type ImpurityKind string
+
+const (
+	// ImpurityMutation is a function changing its input.
+	ImpurityMutation ImpurityKind = "mutated its input"
+	// ImpurityNondeterminism is a function returning different outputs for the same input.
+	ImpurityNondeterminism ImpurityKind = "returned a different output for the same input"
+	// ImpurityAliasing is a function returning memory shared with its input: state aliasing the event in Evolve, or
+	// events aliasing the state in Decide.
+	ImpurityAliasing ImpurityKind = "returned memory shared with its input"
+)
+
+// Impurity is a side effect of Decide or Evolve found by CheckDecide or CheckEvolve.
+type Impurity struct {
+	// Function is either Decide or Evolve.
+	Function string
+	Kind     ImpurityKind
+	// Fields are the paths of the changed fields, e.g. state.PlanId or events[0].plan_created.plan_id.
+	Fields []string
+	Diff   string
+}
+
+func (i Impurity) String() string {
+	return fmt.Sprintf("%s %s, fields %s changed (-before +after):\n%s", i.Function, i.Kind, strings.Join(i.Fields, ", "), i.Diff)
+}
+
+var protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+// CheckDecide decides the command twice, each time on deep copies of the state and the command, and returns the
+// impurities found: a change of the state or the command, different events or errors, and events sharing memory with
+// the state, found by clearing the state after the call.
+func CheckDecide[State any, Command any, Event any](
+	decider *onepiece.Decider[State, Command, Event],
+	state State,
+	command Command,
+) []Impurity {
+	var impurities []Impurity
+
+	firstState, firstCommand := deepCopy(state), deepCopy(command)
+	firstEvents, firstErr := decider.Decide(firstState, firstCommand)
+	impurities = appendImpurity(impurities, "Decide", ImpurityMutation, "state", state, firstState)
+	impurities = appendImpurity(impurities, "Decide", ImpurityMutation, "command", command, firstCommand)
+
+	secondState, secondCommand := deepCopy(state), deepCopy(command)
+	secondEvents, secondErr := decider.Decide(secondState, secondCommand)
+	impurities = appendImpurity(impurities, "Decide", ImpurityNondeterminism, "events", firstEvents, secondEvents)
+	impurities = appendImpurity(impurities, "Decide", ImpurityNondeterminism, "error", errorText(firstErr), errorText(secondErr))
+
+	decided := deepCopy(secondEvents)
+	clearValue(reflect.ValueOf(&secondState).Elem(), map[visit]bool{})
+	impurities = appendImpurity(impurities, "Decide", ImpurityAliasing, "events", decided, secondEvents)
+
+	return impurities
+}
+
+// CheckEvolve evolves the event twice, each time on deep copies of the state and the event, and returns the
+// impurities found: a change of the state or the event, different states, and a state sharing memory with the event,
+// found by clearing the event after the call.
+func CheckEvolve[State any, Command any, Event any](
+	decider *onepiece.Decider[State, Command, Event],
+	state State,
+	event Event,
+) []Impurity {
+	var impurities []Impurity
+
+	firstState, firstEvent := deepCopy(state), deepCopy(event)
+	first := decider.Evolve(firstState, firstEvent)
+	impurities = appendImpurity(impurities, "Evolve", ImpurityMutation, "state", state, firstState)
+	impurities = appendImpurity(impurities, "Evolve", ImpurityMutation, "event", event, firstEvent)
+
+	secondState, secondEvent := deepCopy(state), deepCopy(event)
+	second := decider.Evolve(secondState, secondEvent)
+	impurities = appendImpurity(impurities, "Evolve", ImpurityNondeterminism, "state", first, second)
+
+	evolved := deepCopy(second)
+	clearValue(reflect.ValueOf(&secondEvent).Elem(), map[visit]bool{})
+	impurities = appendImpurity(impurities, "Evolve", ImpurityAliasing, "state", evolved, second)
+
+	return impurities
+}
+
+// AssertPure checks every Evolve of the history, then every Decide of the commands and the Evolve of their events,
+// and fails the test with every impurity found.
+func AssertPure[State any, Command any, Event any](
+	t *testing.T,
+	decider *onepiece.Decider[State, Command, Event],
+	history []Event,
+	commands ...Command,
+) {
+	t.Helper()
+
+	if err := checkPurity(decider, decider.InitialState(), history, commands...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// PurityInvariant is an Invariant checking that the Decide and Evolve of every transition are pure, for
+// CheckProperties.
+func PurityInvariant[State any, Command any, Event any](decider *onepiece.Decider[State, Command, Event]) Invariant[State, Command, Event] {
+	return Invariant[State, Command, Event]{
+		Name: "pure decide and evolve",
+		Check: func(transition Transition[State, Command, Event]) error {
+			return checkPurity(decider, transition.Before, nil, transition.Command)
+		},
+	}
+}
+
+func checkPurity[State any, Command any, Event any](
+	decider *onepiece.Decider[State, Command, Event],
+	state State,
+	history []Event,
+	commands ...Command,
+) error {
+	var impurities []Impurity
+	for _, event := range history {
+		impurities = append(impurities, CheckEvolve(decider, state, event)...)
+		state = decider.Evolve(state, event)
+	}
+	for _, command := range commands {
+		impurities = append(impurities, CheckDecide(decider, state, command)...)
+		events, err := decider.Decide(state, command)
+		if err != nil {
+			continue
+		}
+		for _, event := range events {
+			impurities = append(impurities, CheckEvolve(decider, state, event)...)
+			state = decider.Evolve(state, event)
+		}
+	}
+
+	errs := make([]error, len(impurities))
+	for i, impurity := range impurities {
+		errs[i] = errors.New(impurity.String())
+	}
+	return errors.Join(errs...)
+}
+
+func appendImpurity(impurities []Impurity, function string, kind ImpurityKind, name string, before any, after any) []Impurity {
+	reporter := &pathReporter{root: name}
+	if cmp.Equal(before, after, protocmp.Transform(), cmp.Exporter(exportAll), cmp.Reporter(reporter)) {
+		return impurities
+	}
+
+	return append(impurities, Impurity{
+		Function: function,
+		Kind:     kind,
+		Fields:   reporter.fields,
+		Diff:     cmp.Diff(before, after, protocmp.Transform(), cmp.Exporter(exportAll)),
+	})
+}
+
+func exportAll(reflect.Type) bool {
+	return true
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// pathReporter collects the paths of the values that differ.
+type pathReporter struct {
+	root   string
+	path   cmp.Path
+	fields []string
+}
+
+func (r *pathReporter) PushStep(step cmp.PathStep) {
+	r.path = append(r.path, step)
+}
+
+func (r *pathReporter) PopStep() {
+	r.path = r.path[:len(r.path)-1]
+}
+
+func (r *pathReporter) Report(result cmp.Result) {
+	if result.Equal() {
+		return
+	}
+
+	var builder strings.Builder
+	builder.WriteString(r.root)
+	for _, step := range r.path {
+		switch step := step.(type) {
+		case cmp.StructField:
+			builder.WriteString("." + step.Name())
+		case cmp.MapIndex:
+			builder.WriteString(fmt.Sprintf(".%v", step.Key()))
+		case cmp.SliceIndex:
+			index, other := step.SplitKeys()
+			if index < 0 {
+				index = other
+			}
+			builder.WriteString(fmt.Sprintf("[%d]", index))
+		}
+	}
+	r.fields = append(r.fields, builder.String())
+}
+
+type visit struct {
+	pointer uintptr
+	typ     reflect.Type
+}
+
+// deepCopy copies the value and everything it points to, protobuf messages with proto.Clone. Pointers held by interface
+// values, other than protobuf messages, are kept as they are so sentinel errors still match with errors.Is; a change
+// made through them is not found.
+func deepCopy[T any](value T) T {
+	var copied T
+	copyValue(reflect.ValueOf(&copied).Elem(), reflect.ValueOf(&value).Elem(), map[visit]reflect.Value{})
+	return copied
+}
+
+func copyValue(dst reflect.Value, src reflect.Value, copies map[visit]reflect.Value) {
+	dst, src = exported(dst), exported(src)
+
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		key := visit{pointer: src.Pointer(), typ: src.Type()}
+		if copied, ok := copies[key]; ok {
+			dst.Set(copied)
+			return
+		}
+		if src.Type().Implements(protoMessageType) {
+			dst.Set(reflect.ValueOf(proto.Clone(src.Interface().(proto.Message))))
+			copies[key] = dst
+			return
+		}
+		copied := reflect.New(src.Type().Elem())
+		copies[key] = copied
+		copyValue(copied.Elem(), src.Elem(), copies)
+		dst.Set(copied)
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		if isSharedPointer(src.Elem()) {
+			dst.Set(src)
+			return
+		}
+		copied := reflect.New(src.Elem().Type()).Elem()
+		copyValue(copied, addressable(src.Elem()), copies)
+		dst.Set(copied)
+	case reflect.Struct:
+		for i := 0; i < src.NumField(); i++ {
+			copyValue(dst.Field(i), src.Field(i), copies)
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+		for i := 0; i < src.Len(); i++ {
+			copyValue(dst.Index(i), src.Index(i), copies)
+		}
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			copyValue(dst.Index(i), src.Index(i), copies)
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeMapWithSize(src.Type(), src.Len()))
+		iter := src.MapRange()
+		for iter.Next() {
+			value := reflect.New(src.Type().Elem()).Elem()
+			copyValue(value, addressable(iter.Value()), copies)
+			dst.SetMapIndex(iter.Key(), value)
+		}
+	default:
+		dst.Set(src)
+	}
+}
+
+// clearValue sets to zero every value reachable from the value, so the values sharing its memory change too. Only the
+// exported fields of protobuf messages are cleared, their internal state being left alone. The pointers deepCopy keeps
+// as they are are left alone too, since they are shared with the caller.
+func clearValue(value reflect.Value, visited map[visit]bool) {
+	value = exported(value)
+
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			return
+		}
+		key := visit{pointer: value.Pointer(), typ: value.Type()}
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+		clearValue(value.Elem(), visited)
+	case reflect.Interface:
+		if !value.IsNil() && value.Elem().Kind() == reflect.Pointer && !isSharedPointer(value.Elem()) {
+			clearValue(value.Elem(), visited)
+		}
+	case reflect.Struct:
+		isMessage := reflect.PointerTo(value.Type()).Implements(protoMessageType)
+		for i := 0; i < value.NumField(); i++ {
+			if isMessage && !value.Type().Field(i).IsExported() {
+				continue
+			}
+			field := value.Field(i)
+			if isMessage && field.Kind() == reflect.Interface && !field.IsNil() {
+				// NOTE: a oneof wrapper is cloned with its message, so it is never shared.
+				clearValue(field.Elem(), visited)
+				continue
+			}
+			clearValue(field, visited)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			clearValue(value.Index(i), visited)
+		}
+	case reflect.Map:
+		iter := value.MapRange()
+		for iter.Next() {
+			if iter.Value().Kind() == reflect.Pointer || iter.Value().Kind() == reflect.Interface {
+				clearValue(iter.Value(), visited)
+			}
+		}
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+	default:
+		value.Set(reflect.Zero(value.Type()))
+	}
+}
+
+// exported makes the value of an unexported field settable, since the state of a decider is often unexported. The
+// reflect package never sets unexported fields, so the only way to copy and clear them is to reach their memory with
+// unsafe; the values are only written with values of their own type.
+func exported(value reflect.Value) reflect.Value {
+	if value.CanSet() || !value.CanAddr() {
+		return value
+	}
+	return reflect.NewAt(value.Type(), unsafe.Pointer(value.UnsafeAddr())).Elem()
+}
+
+// isSharedPointer reports whether the value, held by an interface, is a pointer deepCopy keeps as it is.
+func isSharedPointer(value reflect.Value) bool {
+	return value.Kind() == reflect.Pointer && !value.Type().Implements(protoMessageType)
+}
+
+func addressable(value reflect.Value) reflect.Value {
+	copied := reflect.New(value.Type()).Elem()
+	copied.Set(value)
+	return copied
+}
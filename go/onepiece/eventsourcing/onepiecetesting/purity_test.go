@@ -0,0 +1,105 @@
+package onepiecetesting_test
+
+import (
+	"errors"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+	"testing"
+)
+
+// label keeps the last string value evolved, and the number of values decided so far.
+type label struct {
+	name   *string
+	counts []int
+}
+
+func decideLabel(state label, command string) ([]*structpb.Value, error) {
+	return []*structpb.Value{structpb.NewStringValue(command)}, nil
+}
+
+func evolveLabel(state label, event *structpb.Value) label {
+	name := event.GetStringValue()
+	state.name = &name
+	return state
+}
+
+func TestCheckDecide(t *testing.T) {
+	state := label{counts: []int{1}}
+
+	t.Run("finds no impurity in a pure decider", func(t *testing.T) {
+		decider := onepiece.NewDecider(decideLabel, evolveLabel)
+		require.Empty(t, onepiecetesting.CheckDecide(decider, state, "a"))
+	})
+
+	t.Run("finds a mutated state", func(t *testing.T) {
+		decider := onepiece.NewDecider(func(state label, command string) ([]*structpb.Value, error) {
+			state.counts[0]++
+			return decideLabel(state, command)
+		}, evolveLabel)
+
+		impurities := onepiecetesting.CheckDecide(decider, state, "a")
+		require.Len(t, impurities, 1)
+		require.Equal(t, onepiecetesting.ImpurityMutation, impurities[0].Kind)
+		require.Equal(t, []string{"state.counts[0]"}, impurities[0].Fields)
+		require.Equal(t, []int{1}, state.counts)
+	})
+
+	t.Run("finds a non-deterministic output", func(t *testing.T) {
+		calls := 0
+		decider := onepiece.NewDecider(func(state label, command string) ([]*structpb.Value, error) {
+			calls++
+			return []*structpb.Value{structpb.NewNumberValue(float64(calls))}, nil
+		}, evolveLabel)
+
+		impurities := onepiecetesting.CheckDecide(decider, state, "a")
+		require.Len(t, impurities, 1)
+		require.Equal(t, onepiecetesting.ImpurityNondeterminism, impurities[0].Kind)
+		require.Equal(t, []string{"events[0].number_value"}, impurities[0].Fields)
+		require.Contains(t, impurities[0].String(), "Decide returned a different output for the same input")
+	})
+
+	t.Run("keeps sentinel errors in the state", func(t *testing.T) {
+		errClosed := errors.New("label closed")
+		var matched []bool
+		decider := onepiece.NewDecider(func(state error, command string) ([]*structpb.Value, error) {
+			matched = append(matched, errors.Is(state, errClosed))
+			return nil, state
+		}, func(state error, event *structpb.Value) error {
+			return state
+		})
+
+		require.Empty(t, onepiecetesting.CheckDecide(decider, errClosed, "a"))
+		require.Equal(t, []bool{true, true}, matched)
+		require.EqualError(t, errClosed, "label closed")
+	})
+}
+
+func TestCheckEvolve(t *testing.T) {
+	t.Run("finds no impurity in a pure decider", func(t *testing.T) {
+		decider := onepiece.NewDecider(decideLabel, evolveLabel)
+		require.Empty(t, onepiecetesting.CheckEvolve(decider, label{}, structpb.NewStringValue("a")))
+	})
+
+	t.Run("finds a state aliasing the event", func(t *testing.T) {
+		decider := onepiece.NewDecider(decideLabel, func(state label, event *structpb.Value) label {
+			state.name = &event.Kind.(*structpb.Value_StringValue).StringValue
+			return state
+		})
+
+		impurities := onepiecetesting.CheckEvolve(decider, label{}, structpb.NewStringValue("a"))
+		require.Len(t, impurities, 1)
+		require.Equal(t, onepiecetesting.ImpurityAliasing, impurities[0].Kind)
+		require.Equal(t, []string{"state.name"}, impurities[0].Fields)
+	})
+}
+
+func TestAssertPure(t *testing.T) {
+	onepiecetesting.AssertPure(t, counter, []string{"incremented"}, "increment", "increment", "close")
+
+	onepiecetesting.CheckProperties(t, counter, onepiecetesting.PropertyConfig[string]{
+		Commands: onepiecetesting.OneOf("increment", "close"),
+		Seed:     1,
+	}, onepiecetesting.PurityInvariant(counter))
+}
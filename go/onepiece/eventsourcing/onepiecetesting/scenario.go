@@ -0,0 +1,183 @@
+package onepiecetesting
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/go-cmp/cmp"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Scenario asserts a sequence of commands, each one decided on the events given to the scenario plus the events
+// decided by the previous steps.
+type Scenario[State any, Command any, Event any] struct {
+	t       *testing.T
+	decider *onepiece.Decider[State, Command, Event]
+	history []Event
+}
+
+func NewScenario[State any, Command any, Event any](t *testing.T, decider *onepiece.Decider[State, Command, Event]) *Scenario[State, Command, Event] {
+	require.NotNil(t, decider, "decider should not be nil")
+	return &Scenario[State, Command, Event]{t: t, decider: decider}
+}
+
+func (s *Scenario[State, Command, Event]) Given(events ...Event) *Scenario[State, Command, Event] {
+	s.history = append(s.history, events...)
+	return s
+}
+
+// Step asserts, as a subtest, the TestCase set up by the function, then feeds the decided events to the next steps.
+// The scenario stops at the first failing step.
+func (s *Scenario[State, Command, Event]) Step(
+	description string,
+	setup func(t *testing.T, tc *TestCase[State, Command, Event]),
+) *Scenario[State, Command, Event] {
+	s.t.Helper()
+
+	passed := s.t.Run(description, func(t *testing.T) {
+		tc := NewTestCase(t, s.decider).Given(s.history...)
+		setup(t, tc)
+		s.history = append(s.history, tc.assert()...)
+	})
+	if !passed {
+		s.t.FailNow()
+	}
+	return s
+}
+
+// History returns the events given to the scenario followed by the events decided by its steps.
+func (s *Scenario[State, Command, Event]) History() []Event {
+	return append([]Event(nil), s.history...)
+}
+
+// State returns the state evolved from the history.
+func (s *Scenario[State, Command, Event]) State() State {
+	state := s.decider.InitialState()
+	for _, event := range s.history {
+		state = s.decider.Evolve(state, event)
+	}
+	return state
+}
+
+// assertStateFields fails the test unless every field of the expected mapping matches the field of the state with the
+// same name, so spec files can check unexported state. Field names start with a lowercase letter, nested structs are
+// mappings and protobuf messages use their protojson mapping.
+func assertStateFields(t *testing.T, state any, expected yaml.Node) {
+	t.Helper()
+
+	var want any
+	require.NoError(t, expected.Decode(&want), "error decoding state")
+	want = normalizeJSON(t, want)
+	got := pickFields(want, normalizeJSON(t, stateValue(reflect.ValueOf(state))))
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		require.Fail(t, "unexpected state", "diff (-want +got):\n%s", diff)
+	}
+}
+
+// stateValue converts a value to maps, slices and scalars, reading unexported fields too.
+func stateValue(value reflect.Value) any {
+	if !value.IsValid() {
+		return nil
+	}
+	if value.CanInterface() {
+		if msg, ok := value.Interface().(proto.Message); ok {
+			if value.Kind() == reflect.Pointer && value.IsNil() {
+				return nil
+			}
+			data, err := protojson.Marshal(msg)
+			if err != nil {
+				return err.Error()
+			}
+			return json.RawMessage(data)
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		return stateValue(value.Elem())
+	case reflect.Struct:
+		fields := make(map[string]any, value.NumField())
+		for i := 0; i < value.NumField(); i++ {
+			fields[lowerFirst(value.Type().Field(i).Name)] = stateValue(value.Field(i))
+		}
+		return fields
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil
+		}
+		items := make([]any, value.Len())
+		for i := range items {
+			items[i] = stateValue(value.Index(i))
+		}
+		return items
+	case reflect.Map:
+		if value.IsNil() {
+			return nil
+		}
+		entries := make(map[string]any, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			entries[fmt.Sprint(stateValue(iter.Key()))] = stateValue(iter.Value())
+		}
+		return entries
+	case reflect.Bool:
+		return value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint()
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	case reflect.String:
+		return value.String()
+	default:
+		return value.String()
+	}
+}
+
+func lowerFirst(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(r)) + name[size:]
+}
+
+// normalizeJSON round trips a value through JSON, so numbers and nested values compare the same way on both sides.
+func normalizeJSON(t *testing.T, value any) any {
+	t.Helper()
+
+	data, err := json.Marshal(value)
+	require.NoError(t, err, "error encoding state")
+	var normalized any
+	require.NoError(t, json.Unmarshal(data, &normalized), "error decoding state")
+	return normalized
+}
+
+// pickFields keeps the fields of got that are in want, recursively in nested mappings.
+func pickFields(want any, got any) any {
+	wantFields, ok := want.(map[string]any)
+	if !ok {
+		return got
+	}
+	gotFields, ok := got.(map[string]any)
+	if !ok {
+		return got
+	}
+
+	picked := make(map[string]any, len(wantFields))
+	for name, wantValue := range wantFields {
+		if gotValue, ok := gotFields[name]; ok {
+			picked[name] = pickFields(wantValue, gotValue)
+		}
+	}
+	return picked
+}
@@ -1,8 +1,11 @@
 package onepiecetesting
 
 import (
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/straw-hat-team/onepiece/go/onepiece"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/testing/protocmp"
 	"testing"
 )
 
@@ -13,7 +16,16 @@ type TestCase[State any, Command any, Event any] struct {
 	previousEvents []Event
 	command        Command
 	expectedEvents []Event
+	eventMatchers  []EventMatcher
 	expectedError  error
+	errorMatcher   *ErrorMatcher
+	cmpOptions     []cmp.Option
+}
+
+// ErrorMatcher reports whether the error returned by the decider is the expected one.
+type ErrorMatcher struct {
+	Description string
+	Match       func(err error) bool
 }
 
 func (tc *TestCase[State, Command, Event]) Given(events ...Event) *TestCase[State, Command, Event] {
@@ -31,12 +43,41 @@ func (tc *TestCase[State, Command, Event]) Then(event ...Event) *TestCase[State,
 	return tc
 }
 
+// ThenMatching expects one event per matcher, for partial expectations such as Partial. It replaces the events
+// expected with Then.
+func (tc *TestCase[State, Command, Event]) ThenMatching(matchers ...EventMatcher) *TestCase[State, Command, Event] {
+	tc.eventMatchers = append(tc.eventMatchers, matchers...)
+	return tc
+}
+
+// WithOptions adds cmp options to the comparison of the events, e.g. IgnoreFields for generated ids and timestamps.
+func (tc *TestCase[State, Command, Event]) WithOptions(options ...cmp.Option) *TestCase[State, Command, Event] {
+	tc.cmpOptions = append(tc.cmpOptions, options...)
+	return tc
+}
+
 func (tc *TestCase[State, Command, Event]) Catch(err error) *TestCase[State, Command, Event] {
 	tc.expectedError = err
 	return tc
 }
 
+// CatchMatching expects an error accepted by the matcher, for errors that can not be compared with Catch.
+func (tc *TestCase[State, Command, Event]) CatchMatching(matcher ErrorMatcher) *TestCase[State, Command, Event] {
+	tc.errorMatcher = &matcher
+	return tc
+}
+
+// Assert decides the command on the state evolved from the given events, like eventsourcing.NewDecider does, and fails
+// the test unless the events and the error are the expected ones.
 func (tc *TestCase[State, Command, Event]) Assert() {
+	tc.t.Helper()
+	tc.assert()
+}
+
+// assert asserts the test case and returns the events decided by the command.
+func (tc *TestCase[State, Command, Event]) assert() []Event {
+	tc.t.Helper()
+
 	state := tc.decider.InitialState()
 
 	for _, event := range tc.previousEvents {
@@ -44,12 +85,47 @@ func (tc *TestCase[State, Command, Event]) Assert() {
 	}
 
 	if tc.decider.IsTerminal(state) {
-		require.ErrorAs(tc.t, tc.expectedError, onepiece.ErrTerminalState)
+		recordCoverage(tc.decider, tc.command, nil, onepiece.ErrTerminalState)
+		tc.assertError(onepiece.ErrTerminalState)
+		return nil
 	}
 
 	events, err := tc.decider.Decide(state, tc.command)
+	recordCoverage(tc.decider, tc.command, events, err)
+
+	tc.assertEvents(events)
+	tc.assertError(err)
+	return events
+}
+
+func (tc *TestCase[State, Command, Event]) assertEvents(events []Event) {
+	tc.t.Helper()
+
+	options := append([]cmp.Option{protocmp.Transform(), cmpopts.EquateEmpty()}, tc.cmpOptions...)
+
+	if tc.eventMatchers == nil {
+		if diff := cmp.Diff(tc.expectedEvents, events, options...); diff != "" {
+			require.Fail(tc.t, "unexpected events", "diff (-want +got):\n%s", diff)
+		}
+		return
+	}
+
+	require.Len(tc.t, events, len(tc.eventMatchers), "unexpected number of events")
+	for i, matcher := range tc.eventMatchers {
+		if diff := matcher(events[i], options...); diff != "" {
+			require.Fail(tc.t, "unexpected event", "event %d, diff (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+func (tc *TestCase[State, Command, Event]) assertError(err error) {
+	tc.t.Helper()
 
-	require.Equal(tc.t, tc.expectedEvents, events)
+	if tc.errorMatcher != nil {
+		require.Error(tc.t, err, "expected error %s", tc.errorMatcher.Description)
+		require.True(tc.t, tc.errorMatcher.Match(err), "expected error %s, got: %v", tc.errorMatcher.Description, err)
+		return
+	}
 	require.Equal(tc.t, tc.expectedError, err)
 }
 
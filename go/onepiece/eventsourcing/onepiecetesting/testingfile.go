@@ -1,10 +1,15 @@
 package onepiecetesting
 
 import (
+	"errors"
+	"fmt"
+	"github.com/google/go-cmp/cmp"
 	"github.com/straw-hat-team/onepiece/go/onepiece"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -13,16 +18,100 @@ type Message struct {
 	Payload yaml.Node `json:"payload"`
 }
 
+// Exception is the error expected from a case. Every field that is set must match the error. It can be written as a
+// plain string, which is taken as the name.
+type Exception struct {
+	// Name is the name the error was given with WithErrors, matched with errors.Is.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Code is the code of the error, see onepiece.AsDomainError.
+	Code string `json:"code,omitempty" yaml:"code,omitempty"`
+	// Message is a substring of the error message.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+func (e *Exception) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		e.Name = value.Value
+		return nil
+	}
+
+	type exception Exception
+	return value.Decode((*exception)(e))
+}
+
+func (e *Exception) String() string {
+	var fields []string
+	if e.Name != "" {
+		fields = append(fields, "name "+e.Name)
+	}
+	if e.Code != "" {
+		fields = append(fields, "code "+e.Code)
+	}
+	if e.Message != "" {
+		fields = append(fields, fmt.Sprintf("message containing %q", e.Message))
+	}
+	return strings.Join(fields, ", ")
+}
+
+// matcher returns the ErrorMatcher of the exception, resolving its name against the named errors.
+func (e *Exception) matcher(namedErrors map[string]error) (ErrorMatcher, error) {
+	if e.Name == "" && e.Code == "" && e.Message == "" {
+		return ErrorMatcher{}, errors.New("exception must have a name, a code or a message")
+	}
+
+	var target error
+	if e.Name != "" {
+		var ok bool
+		if target, ok = namedErrors[e.Name]; !ok {
+			return ErrorMatcher{}, fmt.Errorf("unknown error name %q, register it with WithErrors", e.Name)
+		}
+	}
+
+	return ErrorMatcher{
+		Description: e.String(),
+		Match: func(err error) bool {
+			if target != nil && !errors.Is(err, target) {
+				return false
+			}
+			if e.Code != "" {
+				domainErr, ok := onepiece.AsDomainError(err)
+				if !ok || domainErr.Code != e.Code {
+					return false
+				}
+			}
+			return strings.Contains(err.Error(), e.Message)
+		},
+	}, nil
+}
+
+// Case is either a single command, with When, Then and Exception, or a scenario of Steps.
 type Case struct {
-	Given     []Message `json:"given" yaml:"given,omitempty"`
-	When      Message   `json:"when" yaml:"when"`
-	Then      []Message `json:"then" yaml:"then,omitempty"`
-	Exception *Message  `json:"Exception,omitempty" yaml:"exception,omitempty"`
+	Given     []Message  `json:"given" yaml:"given,omitempty"`
+	When      Message    `json:"when" yaml:"when,omitempty"`
+	Then      []Message  `json:"then" yaml:"then,omitempty"`
+	Exception *Exception `json:"exception,omitempty" yaml:"exception,omitempty"`
+	Steps     []Step     `json:"steps,omitempty" yaml:"steps,omitempty"`
+}
+
+// Step is a command of a scenario, decided on the given events plus the events decided by the previous steps.
+type Step struct {
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
+	When        Message    `json:"when" yaml:"when"`
+	Then        []Message  `json:"then" yaml:"then,omitempty"`
+	Exception   *Exception `json:"exception,omitempty" yaml:"exception,omitempty"`
+	// State holds the expected fields of the state after the step, unexported fields included, e.g. isArchived: true.
+	State yaml.Node `json:"state,omitempty" yaml:"state,omitempty"`
 }
 
 type UseCase struct {
 	Description string `json:"description" yaml:"description"`
-	Case        Case   `json:"case" yaml:"case"`
+	// Skip skips the use case.
+	Skip bool `json:"skip,omitempty" yaml:"skip,omitempty"`
+	// Only skips every use case of the file that is not marked as only, while working on a few of them.
+	Only bool `json:"only,omitempty" yaml:"only,omitempty"`
+	// Tags select the use case with WithTags.
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Case Case     `json:"case" yaml:"case"`
 }
 
 type TestingFile struct {
@@ -30,29 +119,87 @@ type TestingFile struct {
 }
 
 func NewTestingFile(t *testing.T, fileName string) *TestingFile {
+	tf, err := ReadTestingFile(fileName)
+	require.NoError(t, err)
+	return tf
+}
+
+// ReadTestingFile reads a testing file outside of a test, e.g. to generate its documentation.
+func ReadTestingFile(fileName string) (*TestingFile, error) {
 	file, err := os.ReadFile(fileName)
-	require.NoError(t, err, "error reading file %s", fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", fileName, err)
+	}
 
 	var tf TestingFile
-	err = yaml.Unmarshal(file, &tf)
-	require.NoError(t, err, "error unmarshalling file %s", fileName)
-
-	return &tf
+	if err := yaml.Unmarshal(file, &tf); err != nil {
+		return nil, fmt.Errorf("error unmarshalling file %s: %w", fileName, err)
+	}
+	return &tf, nil
 }
 
 type UnmarshalMessage[Message any] func(eventType string, payload yaml.Node) (Message, error)
 
+// RunOption configures RunTestingFile.
+type RunOption func(config *runConfig)
+
+type runConfig struct {
+	namedErrors map[string]error
+	tags        []string
+	cmpOptions  []cmp.Option
+}
+
+// WithErrors names the errors the exceptions of the file refer to, e.g. {"ErrPlanNotFound": planactor.ErrPlanNotFound}.
+func WithErrors(namedErrors map[string]error) RunOption {
+	return func(config *runConfig) {
+		for name, err := range namedErrors {
+			config.namedErrors[name] = err
+		}
+	}
+}
+
+// WithTags runs only the use cases with at least one of the tags.
+func WithTags(tags ...string) RunOption {
+	return func(config *runConfig) {
+		config.tags = append(config.tags, tags...)
+	}
+}
+
+// WithCmpOptions adds cmp options to the comparison of the events of every use case, see TestCase.WithOptions.
+func WithCmpOptions(options ...cmp.Option) RunOption {
+	return func(config *runConfig) {
+		config.cmpOptions = append(config.cmpOptions, options...)
+	}
+}
+
+// RunTestingFile runs and asserts every use case of the file as a subtest.
 func RunTestingFile[State any, Command any, Event any](
 	t *testing.T,
 	fileName string,
 	decider *onepiece.Decider[State, Command, Event],
 	unmarshalCommand UnmarshalMessage[Command],
 	unmarshalEvent UnmarshalMessage[Event],
+	options ...RunOption,
 ) {
+	config := &runConfig{namedErrors: map[string]error{}}
+	for _, option := range options {
+		option(config)
+	}
+
 	tf := NewTestingFile(t, fileName)
+	only := slices.ContainsFunc(tf.UseCases, func(useCase UseCase) bool { return useCase.Only })
 
 	for _, useCase := range tf.UseCases {
 		t.Run(useCase.Description, func(t *testing.T) {
+			switch {
+			case useCase.Skip:
+				t.Skip("skipped by the testing file")
+			case only && !useCase.Only:
+				t.Skip("skipped by a use case marked as only")
+			case len(config.tags) > 0 && !slices.ContainsFunc(useCase.Tags, func(tag string) bool { return slices.Contains(config.tags, tag) }):
+				t.Skipf("skipped without any of the tags %v", config.tags)
+			}
+
 			givens := make([]Event, len(useCase.Case.Given))
 			for i, message := range useCase.Case.Given {
 				event, err := unmarshalEvent(message.Type, message.Payload)
@@ -60,20 +207,63 @@ func RunTestingFile[State any, Command any, Event any](
 				givens[i] = event
 			}
 
-			command, err := unmarshalCommand(useCase.Case.When.Type, useCase.Case.When.Payload)
-			require.NoError(t, err, "error decoding payload")
-
-			thens := make([]Event, len(useCase.Case.Then))
-			for i, message := range useCase.Case.Then {
-				event, err := unmarshalEvent(message.Type, message.Payload)
-				require.NoError(t, err, "error decoding payload")
-				thens[i] = event
+			if len(useCase.Case.Steps) == 0 {
+				testCase := NewTestCase(t, decider).Given(givens...)
+				setupCase(t, testCase, config, useCase.Case.When, useCase.Case.Then, useCase.Case.Exception, unmarshalCommand, unmarshalEvent)
+				testCase.Assert()
+				return
 			}
 
-			NewTestCase(t, decider).
-				Given(givens...).
-				When(command).
-				Then(thens...)
+			require.Empty(t, useCase.Case.When.Type, "a case has either a when or steps")
+			scenario := NewScenario(t, decider).Given(givens...)
+			for i, step := range useCase.Case.Steps {
+				description := step.Description
+				if description == "" {
+					description = fmt.Sprintf("step %d %s", i+1, step.When.Type)
+				}
+
+				scenario.Step(description, func(t *testing.T, testCase *TestCase[State, Command, Event]) {
+					setupCase(t, testCase, config, step.When, step.Then, step.Exception, unmarshalCommand, unmarshalEvent)
+				})
+				if !step.State.IsZero() {
+					assertStateFields(t, scenario.State(), step.State)
+				}
+			}
 		})
 	}
 }
+
+// setupCase sets the command and the expectations of a TestCase from the messages of a testing file.
+func setupCase[State any, Command any, Event any](
+	t *testing.T,
+	testCase *TestCase[State, Command, Event],
+	config *runConfig,
+	when Message,
+	then []Message,
+	exception *Exception,
+	unmarshalCommand UnmarshalMessage[Command],
+	unmarshalEvent UnmarshalMessage[Event],
+) {
+	t.Helper()
+
+	command, err := unmarshalCommand(when.Type, when.Payload)
+	require.NoError(t, err, "error decoding payload")
+
+	var thens []Event
+	for _, message := range then {
+		event, err := unmarshalEvent(message.Type, message.Payload)
+		require.NoError(t, err, "error decoding payload")
+		thens = append(thens, event)
+	}
+
+	testCase.
+		When(command).
+		Then(thens...).
+		WithOptions(config.cmpOptions...)
+
+	if exception != nil {
+		matcher, err := exception.matcher(config.namedErrors)
+		require.NoError(t, err, "invalid exception")
+		testCase.CatchMatching(matcher)
+	}
+}
@@ -0,0 +1,111 @@
+package onepiecetesting_test
+
+import (
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// failingTestingFileEnv names the testing file run by TestRunTestingFileFailures in its subprocess.
+const failingTestingFileEnv = "ONEPIECE_FAILING_TESTING_FILE"
+
+var errLimitReached = onepiece.NewDomainError("limit_reached", onepiece.StatusFailedPrecondition, "counter limit reached")
+
+// counter counts increments up to two, and is terminal once closed.
+var counter = onepiece.NewDecider(
+	func(state int, command string) ([]string, error) {
+		if state >= 2 {
+			return nil, errLimitReached
+		}
+		return []string{"incremented"}, nil
+	},
+	func(state int, event string) int {
+		if event == "closed" {
+			return -1
+		}
+		return state + 1
+	},
+).WithIsTerminal(func(state int) bool { return state < 0 })
+
+func unmarshalCounterMessage(messages map[string]string) onepiecetesting.UnmarshalMessage[string] {
+	return func(messageType string, _ yaml.Node) (string, error) {
+		message, ok := messages[messageType]
+		if !ok {
+			return "", fmt.Errorf("unknown message %s", messageType)
+		}
+		return message, nil
+	}
+}
+
+func runCounterFile(t *testing.T, fileName string, options ...onepiecetesting.RunOption) {
+	onepiecetesting.RunTestingFile(
+		t,
+		fileName,
+		counter,
+		unmarshalCounterMessage(map[string]string{"Increment": "increment"}),
+		unmarshalCounterMessage(map[string]string{"Incremented": "incremented", "Closed": "closed"}),
+		options...,
+	)
+}
+
+func TestRunTestingFile(t *testing.T) {
+	t.Run("asserts the use cases with the tags", func(t *testing.T) {
+		runCounterFile(t, "testdata/counter.yaml",
+			onepiecetesting.WithErrors(map[string]error{"ErrLimitReached": errLimitReached}),
+			onepiecetesting.WithTags("counter"),
+		)
+	})
+
+	t.Run("runs only the use cases marked as only", func(t *testing.T) {
+		runCounterFile(t, "testdata/only.yaml")
+	})
+
+	t.Run("runs scenarios step by step", func(t *testing.T) {
+		runCounterFile(t, "testdata/scenario.yaml",
+			onepiecetesting.WithErrors(map[string]error{"ErrLimitReached": errLimitReached}),
+		)
+	})
+}
+
+// TestRunTestingFileFailures runs a testing file whose use cases must all fail in a subprocess, since a failing subtest
+// would fail this test too.
+func TestRunTestingFileFailures(t *testing.T) {
+	if fileName := os.Getenv(failingTestingFileEnv); fileName != "" {
+		runCounterFile(t, fileName, onepiecetesting.WithErrors(map[string]error{"ErrLimitReached": errLimitReached}))
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRunTestingFileFailures$", "-test.v")
+	cmd.Env = append(os.Environ(), failingTestingFileEnv+"=testdata/failing.yaml")
+	output, err := cmd.CombinedOutput()
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr, "the failing testing file passed:\n%s", output)
+	for _, description := range []string{
+		"expects_the_wrong_events",
+		"expects_an_exception_the_command_does_not_raise",
+		"expects_another_exception_than_the_one_raised",
+	} {
+		require.Contains(t, string(output), "--- FAIL: TestRunTestingFileFailures/"+description, "%s", output)
+	}
+	require.NotContains(t, string(output), "--- PASS: TestRunTestingFileFailures/", "%s", output)
+}
+
+func TestScenario(t *testing.T) {
+	scenario := onepiecetesting.NewScenario(t, counter).
+		Given("incremented").
+		Step("increments the counter", func(t *testing.T, tc *onepiecetesting.TestCase[int, string, string]) {
+			tc.When("increment").Then("incremented")
+		}).
+		Step("rejects increments past the limit", func(t *testing.T, tc *onepiecetesting.TestCase[int, string, string]) {
+			tc.When("increment").Catch(errLimitReached)
+		})
+
+	require.Equal(t, []string{"incremented", "incremented"}, scenario.History())
+	require.Equal(t, 2, scenario.State())
+}
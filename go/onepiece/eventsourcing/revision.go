@@ -0,0 +1,48 @@
+package eventsourcing
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Text forms of an ExpectedRevision besides a stream revision, used to send the expected revision of a command
+// through transport headers.
+const (
+	ExpectedRevisionAny          = "any"
+	ExpectedRevisionNoStream     = "no_stream"
+	ExpectedRevisionStreamExists = "stream_exists"
+)
+
+// ParseExpectedRevision parses the text form of an ExpectedRevision, either one of the ExpectedRevision constants or
+// a stream revision such as the NextExpectedVersion of a previous Result.
+func ParseExpectedRevision(value string) (ExpectedRevision, error) {
+	switch value {
+	case ExpectedRevisionAny:
+		return Any{}, nil
+	case ExpectedRevisionNoStream:
+		return NoStream{}, nil
+	case ExpectedRevisionStreamExists:
+		return StreamExists{}, nil
+	}
+
+	revision, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expected revision %q: %w", value, err)
+	}
+	return Revision(revision), nil
+}
+
+func FormatExpectedRevision(revision ExpectedRevision) (string, error) {
+	switch r := revision.(type) {
+	case Any:
+		return ExpectedRevisionAny, nil
+	case NoStream:
+		return ExpectedRevisionNoStream, nil
+	case StreamExists:
+		return ExpectedRevisionStreamExists, nil
+	case StreamRevision:
+		return strconv.FormatUint(r.Value, 10), nil
+	default:
+		return "", fmt.Errorf("unsupported expected revision %T", revision)
+	}
+}
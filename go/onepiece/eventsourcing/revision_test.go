@@ -0,0 +1,36 @@
+package eventsourcing_test
+
+import (
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestExpectedRevision(t *testing.T) {
+	tests := []struct {
+		value    string
+		revision eventsourcing.ExpectedRevision
+	}{
+		{value: "any", revision: eventsourcing.Any{}},
+		{value: "no_stream", revision: eventsourcing.NoStream{}},
+		{value: "stream_exists", revision: eventsourcing.StreamExists{}},
+		{value: "42", revision: eventsourcing.Revision(42)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			revision, err := eventsourcing.ParseExpectedRevision(tt.value)
+			require.NoError(t, err)
+			require.Equal(t, tt.revision, revision)
+
+			value, err := eventsourcing.FormatExpectedRevision(tt.revision)
+			require.NoError(t, err)
+			require.Equal(t, tt.value, value)
+		})
+	}
+
+	t.Run("rejects invalid revisions", func(t *testing.T) {
+		_, err := eventsourcing.ParseExpectedRevision("-1")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,63 @@
+package eventsourcing
+
+import (
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+
+const (
+	attributeStreamId            = "onepiece.stream_id"
+	attributeEventCount          = "onepiece.event_count"
+	attributeNextExpectedVersion = "onepiece.next_expected_version"
+)
+
+// WithTracerProvider sets the provider used to create the spans of every dispatch phase. Defaults to the global
+// OpenTelemetry provider.
+func WithTracerProvider(provider trace.TracerProvider) DeciderOption {
+	return func(config *deciderConfig) {
+		config.tracer = provider.Tracer(instrumentationName)
+	}
+}
+
+// WithPropagator sets the propagator used to store the trace context in the metadata of the appended events.
+// Defaults to the global OpenTelemetry propagator.
+func WithPropagator(propagator propagation.TextMapPropagator) DeciderOption {
+	return func(config *deciderConfig) {
+		config.propagator = propagator
+	}
+}
+
+// MetadataCarrier adapts event Metadata to a propagation.TextMapCarrier, so the trace context of a command can be
+// injected into the events it produces and extracted by downstream consumers.
+type MetadataCarrier Metadata
+
+func (c MetadataCarrier) Get(key string) string {
+	value, ok := c[key].(string)
+	if !ok {
+		return ""
+	}
+	return value
+}
+
+func (c MetadataCarrier) Set(key string, value string) {
+	c[key] = value
+}
+
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,136 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecemessage"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+// newCounterHandler returns a handler deciding two events per command, on a state counting the events of the stream.
+func newCounterHandler(options ...eventsourcing.DeciderOption) eventsourcing.CommandHandler[string, string] {
+	decider := onepiece.NewDecider(
+		func(state int, command string) ([]string, error) {
+			if command == "fail" {
+				return nil, onepiece.ErrUnknownCommand
+			}
+			return []string{"incremented", "incremented"}, nil
+		},
+		func(state int, event string) int { return state + 1 },
+	)
+
+	return eventsourcing.NewDecider(
+		decider,
+		func(command string) (string, error) { return "counter-1", nil },
+		func(event string) (eventsourcing.ContentType, []byte, error) {
+			return eventsourcing.ContentTypeJson, []byte(`"` + event + `"`), nil
+		},
+		func(eventType string, data []byte) (string, error) { return string(data[1 : len(data)-1]), nil },
+		func(event string) (*onepiecemessage.MessageType, error) {
+			eventType := onepiecemessage.MessageType("counter." + event)
+			return &eventType, nil
+		},
+		options...,
+	)
+}
+
+// seedCounter appends one event to the stream of the counter handler.
+func seedCounter(t *testing.T, store eventsourcing.EventStore) {
+	_, err := store.AppendToStream(context.Background(), "counter-1", eventsourcing.NoStream{}, []eventsourcing.EventData{
+		{EventType: "counter.incremented", ContentType: eventsourcing.ContentTypeJson, Data: []byte(`"incremented"`)},
+	})
+	require.NoError(t, err)
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestNewDeciderTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	store := onepiecetesting.NewMemoryEventStore()
+	seedCounter(t, store)
+
+	handler := newCounterHandler(
+		eventsourcing.WithTracerProvider(provider),
+		eventsourcing.WithPropagator(propagation.TraceContext{}),
+	)
+	_, err := handler(context.Background(), store, "increment", nil)
+	require.NoError(t, err)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	dispatch, ok := spans["onepiece.Dispatch"]
+	require.True(t, ok, "missing dispatch span")
+	require.False(t, dispatch.Parent().IsValid())
+	require.Equal(t, "counter-1", spanAttribute(dispatch, "onepiece.stream_id").AsString())
+
+	for name, eventCount := range map[string]int64{
+		"onepiece.ReadStream":     1,
+		"onepiece.Evolve":         1,
+		"onepiece.Decide":         2,
+		"onepiece.Marshal":        2,
+		"onepiece.AppendToStream": 2,
+	} {
+		span, ok := spans[name]
+		require.True(t, ok, "missing span %s", name)
+		require.Equal(t, dispatch.SpanContext().TraceID(), span.SpanContext().TraceID(), name)
+		require.Equal(t, dispatch.SpanContext().SpanID(), span.Parent().SpanID(), "%s is not a child of the dispatch", name)
+		require.Equal(t, eventCount, spanAttribute(span, "onepiece.event_count").AsInt64(), name)
+	}
+
+	marshal := spans["onepiece.Marshal"]
+	traceparent := fmt.Sprintf("00-%s-%s-01", marshal.SpanContext().TraceID(), marshal.SpanContext().SpanID())
+	stored := store.Stream("counter-1")[1:]
+	require.Len(t, stored, 2)
+	for _, event := range stored {
+		metadata, err := event.DecodedMetadata()
+		require.NoError(t, err)
+		require.Equal(t, traceparent, metadata["traceparent"])
+	}
+}
+
+func TestMetadataCarrier(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	propagator := propagation.TraceContext{}
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "dispatch")
+	metadata := eventsourcing.Metadata{"$correlationId": "0c8e6bd4-5bd0-4b4a-8d8e-b0bd0ad4bf6c"}
+	propagator.Inject(ctx, eventsourcing.MetadataCarrier(metadata))
+	span.End()
+
+	require.Contains(t, metadata, "traceparent")
+
+	consumerCtx := propagator.Extract(context.Background(), eventsourcing.MetadataCarrier(metadata))
+	_, consumerSpan := provider.Tracer("test").Start(consumerCtx, "consume")
+	consumerSpan.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	require.Equal(t, spans[0].SpanContext.TraceID(), spans[1].SpanContext.TraceID())
+	require.Equal(t, spans[0].SpanContext.SpanID(), spans[1].Parent.SpanID())
+}
+
+func TestMetadataCarrierIgnoresNonStringValues(t *testing.T) {
+	carrier := eventsourcing.MetadataCarrier{"traceparent": 42}
+
+	require.Equal(t, "", carrier.Get("traceparent"))
+	require.Equal(t, "", carrier.Get("tracestate"))
+}
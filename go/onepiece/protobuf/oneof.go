@@ -0,0 +1,75 @@
+package protobuf
+
+import (
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	ErrOneofNotFound   = errors.New("message does not declare exactly one oneof")
+	ErrOneofNotSet     = errors.New("oneof is not set")
+	ErrOneofNotMessage = errors.New("oneof field is not a message")
+)
+
+// Oneof returns the descriptor of the single oneof declared by a Command or Event wrapper message.
+func Oneof[Message proto.Message](msg Message) (protoreflect.OneofDescriptor, error) {
+	oneofs := msg.ProtoReflect().Descriptor().Oneofs()
+	if oneofs.Len() != 1 {
+		return nil, fmt.Errorf("%w: %s", ErrOneofNotFound, MessageFullName(msg))
+	}
+	return oneofs.Get(0), nil
+}
+
+// OneofVariants returns a new wrapper message for every field of the oneof, each one holding an empty variant.
+func OneofVariants[Message proto.Message](msg Message) ([]Message, error) {
+	oneof, err := Oneof(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := oneof.Fields()
+	variants := make([]Message, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		variant, err := NewOneofVariant(msg, fields.Get(i))
+		if err != nil {
+			return nil, err
+		}
+		variants[i] = variant
+	}
+
+	return variants, nil
+}
+
+// NewOneofVariant returns a new wrapper message with the given oneof field set to an empty message.
+func NewOneofVariant[Message proto.Message](msg Message, field protoreflect.FieldDescriptor) (Message, error) {
+	var zero Message
+	if field.Message() == nil {
+		return zero, fmt.Errorf("%w: %s", ErrOneofNotMessage, field.FullName())
+	}
+
+	wrapper := msg.ProtoReflect().Type().New()
+	wrapper.Set(field, wrapper.NewField(field))
+
+	return wrapper.Interface().(Message), nil
+}
+
+// OneofValue returns the message stored in the oneof of a Command or Event wrapper message.
+func OneofValue[Message proto.Message](msg Message) (proto.Message, error) {
+	oneof, err := Oneof(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	reflectMsg := msg.ProtoReflect()
+	field := reflectMsg.WhichOneof(oneof)
+	if field == nil {
+		return nil, fmt.Errorf("%w: %s", ErrOneofNotSet, oneof.FullName())
+	}
+	if field.Message() == nil {
+		return nil, fmt.Errorf("%w: %s", ErrOneofNotMessage, field.FullName())
+	}
+
+	return reflectMsg.Get(field).Message().Interface(), nil
+}
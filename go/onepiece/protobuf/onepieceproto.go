@@ -12,6 +12,11 @@ func (s FullName) String() string {
 	return string(s)
 }
 
+// Name returns the short name of the message, the last token of its full name.
+func (s FullName) Name() string {
+	return string(protoreflect.FullName(s).Name())
+}
+
 func (s FullName) AsMessageType() (*onepiecemessage.MessageType, error) {
 	return onepiecemessage.NewMessageType(string(s))
 }
@@ -0,0 +1,107 @@
+package protobuf
+
+import (
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// OneofRegistry resolves the variants of a Command or Event oneof wrapper by the short or full name of their
+// message, so payloads can be decoded and wrapped without hand-written switches.
+type OneofRegistry[Message proto.Message] struct {
+	fields []protoreflect.FieldDescriptor
+	byName map[string]protoreflect.FieldDescriptor
+}
+
+func NewOneofRegistry[Message proto.Message]() (*OneofRegistry[Message], error) {
+	var msg Message
+	oneof, err := Oneof(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &OneofRegistry[Message]{
+		byName: make(map[string]protoreflect.FieldDescriptor),
+	}
+
+	fields := oneof.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if field.Message() == nil {
+			continue
+		}
+
+		registry.fields = append(registry.fields, field)
+		registry.byName[string(field.Message().Name())] = field
+		registry.byName[string(field.Message().FullName())] = field
+	}
+
+	return registry, nil
+}
+
+func MustNewOneofRegistry[Message proto.Message]() *OneofRegistry[Message] {
+	registry, err := NewOneofRegistry[Message]()
+	if err != nil {
+		panic(err)
+	}
+	return registry
+}
+
+// Names returns the full names of the variant messages, in declaration order.
+func (r *OneofRegistry[Message]) Names() []FullName {
+	names := make([]FullName, len(r.fields))
+	for i, field := range r.fields {
+		names[i] = FullName(field.Message().FullName())
+	}
+	return names
+}
+
+// Lookup returns the oneof field holding the variant message with the given short or full name.
+func (r *OneofRegistry[Message]) Lookup(name string) (protoreflect.FieldDescriptor, bool) {
+	field, ok := r.byName[name]
+	return field, ok
+}
+
+// New returns an empty variant message with the given short or full name.
+func (r *OneofRegistry[Message]) New(name string) (proto.Message, error) {
+	field, ok := r.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", onepiece.ErrUnknownMessage, name)
+	}
+
+	var msg Message
+	return msg.ProtoReflect().Type().New().NewField(field).Message().Interface(), nil
+}
+
+// Wrap sets the variant message into a new wrapper message.
+func (r *OneofRegistry[Message]) Wrap(variant proto.Message) (Message, error) {
+	var msg Message
+
+	name := variant.ProtoReflect().Descriptor().FullName()
+	field, ok := r.Lookup(string(name))
+	if !ok {
+		return msg, fmt.Errorf("%w: %s", onepiece.ErrUnknownMessage, name)
+	}
+
+	wrapper := msg.ProtoReflect().Type().New()
+	wrapper.Set(field, protoreflect.ValueOfMessage(variant.ProtoReflect()))
+
+	return wrapper.Interface().(Message), nil
+}
+
+// DecodeJSON decodes the protojson payload of the variant with the given short or full name and wraps it.
+func (r *OneofRegistry[Message]) DecodeJSON(name string, data []byte) (Message, error) {
+	var msg Message
+
+	variant, err := r.New(name)
+	if err != nil {
+		return msg, err
+	}
+	if err := protojson.Unmarshal(data, variant); err != nil {
+		return msg, err
+	}
+
+	return r.Wrap(variant)
+}
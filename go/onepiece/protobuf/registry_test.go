@@ -0,0 +1,48 @@
+package protobuf_test
+
+import (
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/protobuf"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"testing"
+)
+
+func TestOneofRegistry(t *testing.T) {
+	registry := protobuf.MustNewOneofRegistry[*structpb.Value]()
+
+	t.Run("lists message variants only", func(t *testing.T) {
+		require.Equal(t, []protobuf.FullName{"google.protobuf.Struct", "google.protobuf.ListValue"}, registry.Names())
+	})
+
+	t.Run("looks up variants by short and full name", func(t *testing.T) {
+		short, ok := registry.Lookup("ListValue")
+		require.True(t, ok)
+		full, ok := registry.Lookup("google.protobuf.ListValue")
+		require.True(t, ok)
+		require.Equal(t, short, full)
+
+		_, ok = registry.Lookup("NullValue")
+		require.False(t, ok)
+	})
+
+	t.Run("decodes and wraps a variant", func(t *testing.T) {
+		value, err := registry.DecodeJSON("ListValue", []byte(`[1, "two"]`))
+		require.NoError(t, err)
+
+		want := structpb.NewListValue(&structpb.ListValue{Values: []*structpb.Value{
+			structpb.NewNumberValue(1),
+			structpb.NewStringValue("two"),
+		}})
+		require.True(t, proto.Equal(want, value))
+	})
+
+	t.Run("rejects unknown variants", func(t *testing.T) {
+		_, err := registry.DecodeJSON("Timestamp", []byte(`{}`))
+		require.ErrorIs(t, err, onepiece.ErrUnknownMessage)
+
+		_, err = registry.Wrap(&structpb.Value{})
+		require.ErrorIs(t, err, onepiece.ErrUnknownMessage)
+	})
+}
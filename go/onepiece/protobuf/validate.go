@@ -0,0 +1,105 @@
+package protobuf
+
+import (
+	"errors"
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecemessage"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrInvalidWiring = errors.New("invalid decider wiring")
+)
+
+// Validate checks the functions given to eventsourcing.NewDecider against every variant of the Command and Event
+// oneof wrappers, so missing mappings are reported at boot time instead of on the first live command.
+// Every problem found is returned at once, joined into a single error.
+func Validate[Command proto.Message, Event proto.Message](
+	getStreamId eventsourcing.StreamId[Command],
+	marshalEvent eventsourcing.MarshalEvent[Event],
+	unmarshalEvent eventsourcing.UnmarshalEvent[Event],
+	getEventType eventsourcing.GetEventType[Event],
+) error {
+	var errs []error
+
+	var command Command
+	commands, err := OneofVariants(command)
+	if err != nil {
+		errs = append(errs, wiringError("command", MessageFullName(command), err))
+	}
+	for _, command := range commands {
+		errs = append(errs, validateCommand(command, getStreamId)...)
+	}
+
+	var event Event
+	events, err := OneofVariants(event)
+	if err != nil {
+		errs = append(errs, wiringError("event", MessageFullName(event), err))
+	}
+	for _, event := range events {
+		errs = append(errs, validateEvent(event, marshalEvent, unmarshalEvent, getEventType)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateCommand[Command proto.Message](command Command, getStreamId eventsourcing.StreamId[Command]) []error {
+	var errs []error
+
+	variant, err := OneofValue(command)
+	if err != nil {
+		return []error{wiringError("command", MessageFullName(command), err)}
+	}
+	name := MessageFullName(variant)
+
+	if _, err := name.AsMessageType(); err != nil {
+		errs = append(errs, wiringError("command", name, err))
+	}
+	if _, err := getStreamId(command); err != nil {
+		errs = append(errs, wiringError("command", name, fmt.Errorf("stream id: %w", err)))
+	}
+
+	return errs
+}
+
+func validateEvent[Event proto.Message](
+	event Event,
+	marshalEvent eventsourcing.MarshalEvent[Event],
+	unmarshalEvent eventsourcing.UnmarshalEvent[Event],
+	getEventType eventsourcing.GetEventType[Event],
+) []error {
+	variant, err := OneofValue(event)
+	if err != nil {
+		return []error{wiringError("event", MessageFullName(event), err)}
+	}
+	name := MessageFullName(variant)
+
+	eventType, err := getEventType(event)
+	if err != nil {
+		return []error{wiringError("event", name, fmt.Errorf("event type: %w", err))}
+	}
+	if _, err := onepiecemessage.NewMessageType(eventType.String()); err != nil {
+		return []error{wiringError("event", name, err)}
+	}
+
+	_, data, err := marshalEvent(event)
+	if err != nil {
+		return []error{wiringError("event", name, fmt.Errorf("marshal: %w", err))}
+	}
+
+	decoded, err := unmarshalEvent(eventType.String(), data)
+	if err != nil {
+		return []error{wiringError("event", name, fmt.Errorf("unmarshal %s: %w", eventType, err))}
+	}
+
+	if !proto.Equal(event, decoded) {
+		return []error{wiringError("event", name, fmt.Errorf("round trip through %s does not preserve the event", eventType))}
+	}
+
+	return nil
+}
+
+func wiringError(kind string, name FullName, err error) error {
+	return fmt.Errorf("%w: %s %s: %w", ErrInvalidWiring, kind, name, err)
+}
@@ -0,0 +1,120 @@
+package golang
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/protobuf"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"time"
+)
+
+const (
+	defaultClientTimeout = 5 * time.Second
+	defaultRetryBackoff  = 100 * time.Millisecond
+)
+
+type ClientConfig struct {
+	// Name of the service, used to derive the Group when it is not set.
+	Name string
+	// Group is the subject prefix shared by every endpoint. Defaults to svc.onepiece.<Name>.
+	Group string
+	// Timeout bounds every request when the context has no deadline. Defaults to 5s.
+	Timeout time.Duration
+	// MaxRetries is the number of times a request is sent again when no service is listening.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled on every following retry. Defaults to 100ms.
+	RetryBackoff time.Duration
+}
+
+// Client dispatches the variants of a Command oneof to a service started with NewAggregateService.
+type Client[Command proto.Message] struct {
+	nc     *nats.Conn
+	config ClientConfig
+}
+
+func NewClient[Command proto.Message](nc *nats.Conn, config ClientConfig) (*Client[Command], error) {
+	if config.Group == "" {
+		if config.Name == "" {
+			return nil, ErrServiceNameRequired
+		}
+		config.Group = fmt.Sprintf("svc.onepiece.%s", config.Name)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultClientTimeout
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = defaultRetryBackoff
+	}
+
+	return &Client[Command]{nc: nc, config: config}, nil
+}
+
+// Dispatch sends the variant set in the command to its endpoint, with the options as request headers. Rejections
+// are returned as the errors decoded by DecodeError.
+func (c *Client[Command]) Dispatch(
+	ctx context.Context,
+	command Command,
+	opts *eventsourcing.Options,
+) (_ *CommandHandlerResponse, err error) {
+	variant, err := protobuf.OneofValue(command)
+	if err != nil {
+		return nil, err
+	}
+	data, err := protojson.Marshal(variant)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := fmt.Sprintf("%s.%s", c.config.Group, variant.ProtoReflect().Descriptor().Name())
+	ctx, span := tracer.Start(ctx, subject, trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	if err := SetOptionsHeaders(msg.Header, opts); err != nil {
+		return nil, err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier(msg.Header))
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
+	}
+
+	resp, err := c.request(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeResponse(resp)
+}
+
+// request sends the message, retrying with an exponential backoff while no service is listening.
+func (c *Client[Command]) request(ctx context.Context, msg *nats.Msg) (*nats.Msg, error) {
+	backoff := c.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.nc.RequestMsgWithContext(ctx, msg)
+		if err == nil || !errors.Is(err, nats.ErrNoResponders) || attempt >= c.config.MaxRetries {
+			return resp, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,107 @@
+package golang_test
+
+import (
+	"context"
+	"github.com/nats-io/nats.go"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"testing"
+	"time"
+	golang "unstable"
+	"unstable/plandomain/planactor"
+	"unstable/plandomain/planproto"
+)
+
+func TestClient(t *testing.T) {
+	nc := runNats(t)
+	handler := &recordingHandler{}
+
+	svc, err := golang.NewAggregateService[*planproto.Command](nc, golang.ServiceConfig{Name: "plan"}, handler.handle)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = svc.Stop() })
+
+	client, err := golang.NewClient[*planproto.Command](nc, golang.ClientConfig{Name: "plan"})
+	require.NoError(t, err)
+
+	t.Run("dispatches the command with its options", func(t *testing.T) {
+		command := &planproto.Command{Command: &planproto.Command_UpdatePlan{UpdatePlan: &planproto.UpdatePlan{
+			PlanId: "d83a3744-0e53-4fb7-88f7-7ffc831f0090",
+			Title:  "Vacation",
+		}}}
+		correlationId := eventsourcing.CorrelationId("correlation")
+		opts := &eventsourcing.Options{ExpectedRevision: eventsourcing.Revision(7), CorrelationId: &correlationId}
+
+		resp, err := client.Dispatch(context.Background(), command, opts)
+		require.NoError(t, err)
+
+		require.Equal(t, uint64(1), resp.NextExpectedVersion)
+		require.True(t, proto.Equal(command, handler.last()))
+		require.Equal(t, opts, handler.lastOptions())
+	})
+
+	t.Run("rejects commands without a variant", func(t *testing.T) {
+		_, err := client.Dispatch(context.Background(), &planproto.Command{}, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestClientErrors(t *testing.T) {
+	nc := runNats(t)
+	command := &planproto.Command{Command: &planproto.Command_ArchivePlan{ArchivePlan: &planproto.ArchivePlan{
+		PlanId: "d83a3744-0e53-4fb7-88f7-7ffc831f0090",
+	}}}
+	rejectAll := func(_ context.Context, _ *planproto.Command, _ *eventsourcing.Options) (*golang.CommandHandlerResponse, error) {
+		return nil, planactor.ErrPlanNotFound
+	}
+
+	t.Run("fails without responders", func(t *testing.T) {
+		client, err := golang.NewClient[*planproto.Command](nc, golang.ClientConfig{Group: "svc.onepiece.missing"})
+		require.NoError(t, err)
+
+		_, err = client.Dispatch(context.Background(), command, nil)
+		require.ErrorIs(t, err, nats.ErrNoResponders)
+	})
+
+	t.Run("retries until a service responds", func(t *testing.T) {
+		client, err := golang.NewClient[*planproto.Command](nc, golang.ClientConfig{
+			Name:         "plan",
+			MaxRetries:   10,
+			RetryBackoff: 10 * time.Millisecond,
+		})
+		require.NoError(t, err)
+
+		started := make(chan error, 1)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			svc, err := golang.NewAggregateService[*planproto.Command](nc, golang.ServiceConfig{Name: "plan"}, rejectAll)
+			if err == nil {
+				t.Cleanup(func() { _ = svc.Stop() })
+			}
+			started <- err
+		}()
+
+		_, err = client.Dispatch(context.Background(), command, nil)
+		require.NoError(t, <-started)
+		require.ErrorIs(t, err, planactor.ErrPlanNotFound)
+	})
+
+	t.Run("honours the context deadline", func(t *testing.T) {
+		client, err := golang.NewClient[*planproto.Command](nc, golang.ClientConfig{
+			Group:        "svc.onepiece.missing",
+			MaxRetries:   100,
+			RetryBackoff: time.Second,
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = client.Dispatch(ctx, command, nil)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("requires a name or group", func(t *testing.T) {
+		_, err := golang.NewClient[*planproto.Command](nc, golang.ClientConfig{})
+		require.ErrorIs(t, err, golang.ErrServiceNameRequired)
+	})
+}
@@ -11,7 +11,9 @@ import (
 )
 
 func main() {
-	eventStore := golang.MustNewEventStore()
+	golang.SetupTracePropagation()
+	golang.Must(planinfra.Validate())
+	eventStore := eventsourcing.NewEventStoreDB(golang.MustNewEventStore())
 	planID := uuid.Must(uuid.NewV4()).String()
 	command := &planproto.CreatePlan{
 		PlanId: planID,
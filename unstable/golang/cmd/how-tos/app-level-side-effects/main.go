@@ -3,13 +3,19 @@ package main
 import (
 	"context"
 	"errors"
-	"github.com/EventStore/EventStore-Client-Go/v3/esdb"
 	"github.com/gofrs/uuid"
 	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecemetrics"
+	"github.com/straw-hat-team/onepiece/go/onepiece/protobuf"
 	"google.golang.org/protobuf/encoding/protojson"
+	"net/http"
 	"strconv"
+	"time"
 	golang "unstable"
+	"unstable/plandomain/commands/createplan"
 	"unstable/plandomain/planproto"
 	"unstable/planinfra"
 )
@@ -18,17 +24,28 @@ type GetPlanLimitReached func(ctx context.Context, depositAccountId string) (boo
 type GenerateId func() string
 
 type HandlerOptions struct {
-	EventStore          *esdb.Client
+	EventStore          eventsourcing.EventStore
+	Metrics             *onepiecemetrics.Metrics
 	GenerateId          GenerateId
 	GetPlanLimitReached GetPlanLimitReached
 }
 
-func NewHandler(o HandlerOptions) func(command *planproto.CreatePlan) (*golang.CommandHandlerResponse, error) {
-	return func(command *planproto.CreatePlan) (*golang.CommandHandlerResponse, error) {
+func NewHandler(o HandlerOptions) golang.ServiceCommandHandler[*planproto.CreatePlan] {
+	dispatch := eventsourcing.Chain(
+		planinfra.NewDispatchCreatePlan(o.Metrics.ReplayHook()),
+		onepiecemetrics.Middleware[*planproto.CreatePlan, *planproto.Event](o.Metrics, commandType),
+		eventsourcing.Timeout[*planproto.CreatePlan, *planproto.Event](5*time.Second),
+		eventsourcing.Classify[*planproto.CreatePlan, *planproto.Event](
+			eventsourcing.NewErrorClassifier(createplan.ErrPlanExists),
+		),
+		eventsourcing.Recover[*planproto.CreatePlan, *planproto.Event](),
+	)
+
+	return func(ctx context.Context, command *planproto.CreatePlan, opts *eventsourcing.Options) (*golang.CommandHandlerResponse, error) {
 		// NOTE: this could be the side effect.
 		// I said could be because what makes it a side effect is depending upon
 		// the runtime environment dependency injection.
-		limitReached, err := o.GetPlanLimitReached(context.Background(), command.DepositAccountId)
+		limitReached, err := o.GetPlanLimitReached(ctx, command.DepositAccountId)
 		if err != nil {
 			return nil, err
 		}
@@ -45,17 +62,12 @@ func NewHandler(o HandlerOptions) func(command *planproto.CreatePlan) (*golang.C
 			command.PlanId = o.GenerateId()
 		}
 
-		result, err := planinfra.DispatchCreatePlan(
-			context.Background(),
-			o.EventStore,
-			command,
-			&eventsourcing.Options{
-				ExpectedRevision: eventsourcing.NoStream{},
-				Metadata:         nil,
-				CorrelationId:    eventsourcing.NewCorrelationId(),
-				CausationId:      eventsourcing.NewCausationId(),
-			},
-		)
+		// NOTE: creating a plan never expects an existing stream, unless the client asked for a specific revision.
+		if opts.ExpectedRevision == nil {
+			opts.ExpectedRevision = eventsourcing.NoStream{}
+		}
+
+		result, err := dispatch(ctx, o.EventStore, command, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -67,6 +79,7 @@ func NewHandler(o HandlerOptions) func(command *planproto.CreatePlan) (*golang.C
 }
 
 func main() {
+	golang.SetupTracePropagation()
 	ctx := context.Background()
 	nc, js := golang.NewNats()
 	eventStore := golang.MustNewEventStore()
@@ -75,6 +88,11 @@ func main() {
 	getPlanLimitReachedService, err := NewGetPlanLimitReached(ctx, kv, 50)
 	golang.Must(err)
 
+	metrics := onepiecemetrics.MustNewMetrics(prometheus.DefaultRegisterer)
+	go func() {
+		golang.Must(http.ListenAndServe(":2112", promhttp.Handler()))
+	}()
+
 	// NOTE: Ignore how the service works, this is just to make a point.
 	_, err = golang.NewService[*planproto.CreatePlan](
 		nc,
@@ -83,7 +101,8 @@ func main() {
 		unmarshalCommand,
 		NewHandler(
 			HandlerOptions{
-				EventStore:          eventStore,
+				EventStore:          eventsourcing.NewEventStoreDB(eventStore),
+				Metrics:             metrics,
 				GenerateId:          generateUuid,
 				GetPlanLimitReached: getPlanLimitReachedService,
 			},
@@ -117,6 +136,10 @@ func NewGetPlanLimitReached(ctx context.Context, kv jetstream.KeyValue, limit ui
 	}, nil
 }
 
+func commandType(command *planproto.CreatePlan) string {
+	return protobuf.MessageFullName(command).String()
+}
+
 func generateUuid() string {
 	return uuid.Must(uuid.NewV4()).String()
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecegrpc"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecehttp"
+	"google.golang.org/grpc"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	golang "unstable"
+	"unstable/plandomain/planproto"
+	"unstable/planinfra"
+)
+
+func main() {
+	golang.SetupTracePropagation()
+	golang.Must(planinfra.Validate())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	nc, _ := golang.NewNats()
+	defer nc.Drain()
+	eventStore := eventsourcing.NewEventStoreDB(golang.MustNewEventStore())
+
+	_, err := golang.NewAggregateService[*planproto.Command](
+		nc,
+		golang.ServiceConfig{
+			Name:        "plan",
+			Version:     "1.0.0",
+			Description: "Creates, updates, archives and drains plans",
+		},
+		golang.NewServiceCommandHandler(eventStore, planinfra.DispatchCommand),
+	)
+	golang.Must(err)
+
+	queue, err := golang.NewCommandQueue[*planproto.Command](ctx, nc, golang.CommandQueueConfig{Name: "plans"})
+	golang.Must(err)
+	consumer, err := queue.Consume(ctx, golang.NewServiceCommandHandler(eventStore, planinfra.DispatchCommand))
+	golang.Must(err)
+	defer consumer.Stop()
+
+	gateway, err := onepiecehttp.NewCommandHandler[*planproto.Command, *planproto.Event](eventStore, planinfra.DispatchCommand)
+	golang.Must(err)
+	httpServer := &http.Server{Addr: ":8080", Handler: gateway}
+	go func() {
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			golang.Must(err)
+		}
+	}()
+
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(onepiecegrpc.UnaryTracingInterceptor(nil, nil)))
+	golang.Must(onepiecegrpc.RegisterService[*planproto.Command, *planproto.Event](
+		grpcServer,
+		"com.hmbradley.deposit.plan.PlanService",
+		eventStore,
+		planinfra.DispatchCommand,
+	))
+	lis, err := net.Listen("tcp", ":9090")
+	golang.Must(err)
+	go func() {
+		golang.Must(grpcServer.Serve(lis))
+	}()
+
+	<-ctx.Done()
+	grpcServer.GracefulStop()
+	golang.Must(httpServer.Shutdown(context.Background()))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"io"
+	"os"
+	"path/filepath"
+	golang "unstable"
+)
+
+// spec-docs generates the living documentation of the testing files given as arguments, e.g.
+// go run ./cmd/spec-docs -title Plan -out plan.md filetesting/*.yaml
+func main() {
+	title := flag.String("title", "Specs", "title of the documentation")
+	format := flag.String("format", "", "markdown or html, from the extension of the output file by default")
+	out := flag.String("out", "", "output file, stdout by default")
+	flag.Parse()
+
+	var files []*onepiecetesting.TestingFile
+	for _, fileName := range flag.Args() {
+		file, err := onepiecetesting.ReadTestingFile(fileName)
+		golang.Must(err)
+		files = append(files, file)
+	}
+	docs := onepiecetesting.NewDocs(*title, files...)
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		golang.Must(err)
+		defer file.Close()
+		w = file
+	}
+	if *format == "" && (filepath.Ext(*out) == ".html" || filepath.Ext(*out) == ".htm") {
+		*format = "html"
+	}
+
+	switch *format {
+	case "html":
+		golang.Must(docs.WriteHTML(w))
+	case "", "markdown":
+		golang.Must(docs.WriteMarkdown(w))
+	default:
+		golang.Must(fmt.Errorf("unknown format %q", *format))
+	}
+}
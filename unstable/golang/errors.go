@@ -0,0 +1,123 @@
+package golang
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	services "github.com/nats-io/nats.go/micro"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"net/http"
+	"strconv"
+)
+
+// Headers carrying the DomainError of a failed request, next to the NATS micro error headers. The response data holds
+// the details of the error as JSON.
+const (
+	HeaderErrorCode   = "Onepiece-Error-Code"
+	HeaderErrorStatus = "Onepiece-Error-Status"
+)
+
+// internalErrorMessage replaces the description of any error that is not a domain error, which may expose internals.
+const internalErrorMessage = "internal error"
+
+// ServiceError is an error response that does not carry a DomainError, such as an infrastructure failure.
+type ServiceError struct {
+	Code        string
+	Description string
+}
+
+func (e *ServiceError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// respondError maps domain errors to the NATS micro error code of their HTTP status plus the Onepiece error headers,
+// and anything else to a 500 error.
+func respondError(req services.Request, err error) error {
+	msg, err := newErrorMsg("", err)
+	if err != nil {
+		return err
+	}
+
+	headers := services.Headers(msg.Header)
+	return req.Error(
+		headers.Get(services.ErrorCodeHeader),
+		headers.Get(services.ErrorHeader),
+		msg.Data,
+		services.WithHeaders(headers),
+	)
+}
+
+// newErrorMsg encodes an error the way respondError does, for replies sent outside a NATS micro service. Errors that
+// are not domain errors are only described as internal errors, the callers logging the cause.
+func newErrorMsg(subject string, err error) (*nats.Msg, error) {
+	msg := nats.NewMsg(subject)
+
+	domainErr, ok := onepiece.AsDomainError(err)
+	if !ok {
+		msg.Header.Set(services.ErrorCodeHeader, strconv.Itoa(http.StatusInternalServerError))
+		msg.Header.Set(services.ErrorHeader, internalErrorMessage)
+		return msg, nil
+	}
+
+	if len(domainErr.Details) > 0 {
+		details, err := json.Marshal(domainErr.Details)
+		if err != nil {
+			return nil, err
+		}
+		msg.Data = details
+	}
+	// NOTE: an empty error header reads as a success, so a domain error without a message is described by its code.
+	description := domainErr.Message
+	if description == "" {
+		description = domainErr.Code
+	}
+	msg.Header.Set(services.ErrorCodeHeader, strconv.Itoa(domainErr.Status.HTTPStatus()))
+	msg.Header.Set(services.ErrorHeader, description)
+	msg.Header.Set(HeaderErrorCode, domainErr.Code)
+	msg.Header.Set(HeaderErrorStatus, string(domainErr.Status))
+
+	return msg, nil
+}
+
+// DecodeError returns the error carried by a NATS micro response, or nil when the request succeeded. Domain errors are
+// resolved against onepiece.DefaultErrorRegistry, so errors.Is matches the errors returned by the deciders.
+func DecodeError(msg *nats.Msg) error {
+	description := msg.Header.Get(services.ErrorHeader)
+	if description == "" {
+		return nil
+	}
+
+	code := msg.Header.Get(HeaderErrorCode)
+	if code == "" {
+		return &ServiceError{
+			Code:        msg.Header.Get(services.ErrorCodeHeader),
+			Description: description,
+		}
+	}
+
+	domainErr := &onepiece.DomainError{
+		Code:    code,
+		Status:  onepiece.ErrorStatus(msg.Header.Get(HeaderErrorStatus)),
+		Message: description,
+	}
+	if len(msg.Data) > 0 {
+		if err := json.Unmarshal(msg.Data, &domainErr.Details); err != nil {
+			return err
+		}
+	}
+
+	return onepiece.ResolveDomainError(domainErr)
+}
+
+// DecodeResponse returns the CommandHandlerResponse of a NATS micro response, or the error decoded by DecodeError.
+func DecodeResponse(msg *nats.Msg) (*CommandHandlerResponse, error) {
+	if err := DecodeError(msg); err != nil {
+		return nil, err
+	}
+
+	var result CommandHandlerResponse
+	if err := json.Unmarshal(msg.Data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,79 @@
+package golang_test
+
+import (
+	"context"
+	"errors"
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	golang "unstable"
+	"unstable/plandomain/planactor"
+	"unstable/plandomain/planproto"
+)
+
+func TestDecodeError(t *testing.T) {
+	nc := runNats(t)
+
+	var handlerErr error
+	svc, err := golang.NewAggregateService[*planproto.Command](nc, golang.ServiceConfig{Name: "plan"},
+		func(_ context.Context, _ *planproto.Command, _ *eventsourcing.Options) (*golang.CommandHandlerResponse, error) {
+			return nil, handlerErr
+		})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = svc.Stop() })
+
+	request := func(t *testing.T, data string) error {
+		t.Helper()
+		msg, err := nc.Request("svc.onepiece.plan.ArchivePlan", []byte(data), 5*time.Second)
+		require.NoError(t, err)
+		return golang.DecodeError(msg)
+	}
+
+	t.Run("resolves domain errors", func(t *testing.T) {
+		handlerErr = planactor.ErrPlanNotFound
+		err := request(t, `{"planId": "d83a3744-0e53-4fb7-88f7-7ffc831f0090"}`)
+
+		require.ErrorIs(t, err, planactor.ErrPlanNotFound)
+		domainErr, ok := onepiece.AsDomainError(err)
+		require.True(t, ok)
+		require.Equal(t, onepiece.StatusNotFound, domainErr.Status)
+	})
+
+	t.Run("resolves registered sentinel errors", func(t *testing.T) {
+		handlerErr = &eventsourcing.ClassifiedError{Kind: eventsourcing.ErrorKindConcurrency, Err: eventsourcing.ErrOptimisticConcurrency}
+		err := request(t, `{}`)
+
+		require.ErrorIs(t, err, eventsourcing.ErrOptimisticConcurrency)
+	})
+
+	t.Run("rejects invalid commands", func(t *testing.T) {
+		err := request(t, `{"planId": 1}`)
+
+		require.ErrorIs(t, err, eventsourcing.ErrInvalidCommand)
+		domainErr, ok := onepiece.AsDomainError(err)
+		require.True(t, ok)
+		require.NotEmpty(t, domainErr.Details["reason"])
+	})
+
+	t.Run("reports other errors as service errors", func(t *testing.T) {
+		handlerErr = errors.New("connection refused")
+		err := request(t, `{}`)
+
+		var serviceErr *golang.ServiceError
+		require.ErrorAs(t, err, &serviceErr)
+		require.Equal(t, "500", serviceErr.Code)
+		require.Equal(t, "internal error", serviceErr.Description)
+	})
+
+	t.Run("describes domain errors without a message by their code", func(t *testing.T) {
+		handlerErr = &onepiece.DomainError{Code: "plan_locked", Status: onepiece.StatusFailedPrecondition}
+		err := request(t, `{}`)
+
+		domainErr, ok := onepiece.AsDomainError(err)
+		require.True(t, ok)
+		require.Equal(t, "plan_locked", domainErr.Code)
+		require.Equal(t, "plan_locked", domainErr.Message)
+	})
+}
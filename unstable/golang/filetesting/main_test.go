@@ -0,0 +1,34 @@
+package filetesting_test
+
+import (
+	"flag"
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"os"
+	"testing"
+	"unstable/plandomain/planactor"
+)
+
+var specCoverage = flag.String("spec-coverage", "", "write the spec coverage report to the file, as JSON when it ends with .json")
+
+func TestMain(m *testing.M) {
+	onepiecetesting.TrackCoverage(planactor.Decider, "plan", map[string]error{
+		"ErrPlanExists":     planactor.ErrPlanExists,
+		"ErrPlanNotFound":   planactor.ErrPlanNotFound,
+		"ErrPlanArchived":   planactor.ErrPlanArchived,
+		"ErrPlanUnarchived": planactor.ErrPlanUnarchived,
+		"ErrPlanDrained":    planactor.ErrPlanDrained,
+	})
+
+	code := m.Run()
+
+	if *specCoverage != "" {
+		if err := onepiecetesting.WriteCoverageFile(*specCoverage); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+	} else if testing.Verbose() {
+		_ = onepiecetesting.WriteCoverage(os.Stdout, onepiecetesting.CoverageFormatText)
+	}
+	os.Exit(code)
+}
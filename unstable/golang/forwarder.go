@@ -0,0 +1,401 @@
+package golang
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/EventStore/EventStore-Client-Go/v3/esdb"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecemetrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of the messages published by a Forwarder. The correlation and causation ids reuse the command headers.
+const (
+	HeaderEventId         = "Onepiece-Event-Id"
+	HeaderEventType       = "Onepiece-Event-Type"
+	HeaderStreamId        = "Onepiece-Stream-Id"
+	HeaderEventRevision   = "Onepiece-Event-Revision"
+	HeaderCommitPosition  = "Onepiece-Commit-Position"
+	HeaderPreparePosition = "Onepiece-Prepare-Position"
+	HeaderContentType     = "Content-Type"
+)
+
+const (
+	defaultForwarderPrefix = "events"
+	defaultLagInterval     = 10 * time.Second
+	// maxCountedEvents bounds the events read by EventStoreSource.CountAfter, the lag saturating at that count.
+	maxCountedEvents = 10_000
+)
+
+var ErrForwarderStreamRequired = errors.New("forwarder stream is required")
+
+// EventPosition is the position of an event in the log of all the events of an event store.
+type EventPosition struct {
+	Commit  uint64
+	Prepare uint64
+}
+
+// RecordedEvent is an event read from an event store.
+type RecordedEvent struct {
+	Id          string
+	StreamId    string
+	Type        string
+	Revision    uint64
+	Position    EventPosition
+	ContentType string
+	Data        []byte
+	// Metadata is the JSON metadata appended with the event, see eventsourcing.Metadata.
+	Metadata  []byte
+	CreatedAt time.Time
+}
+
+// EventSource streams the events of an event store.
+type EventSource interface {
+	// Subscribe calls handle with every event after the position, or from the beginning when the position is nil,
+	// until the context is done or handle fails.
+	Subscribe(ctx context.Context, from *EventPosition, handle func(ctx context.Context, event *RecordedEvent) error) error
+}
+
+// EventCounter is implemented by the EventSources able to count the events after a position, which a Forwarder with
+// Metrics records as its lag.
+type EventCounter interface {
+	CountAfter(ctx context.Context, from EventPosition) (uint64, error)
+}
+
+// SubjectMapper returns the subject of an event, relative to the subject prefix of the Forwarder.
+type SubjectMapper func(event *RecordedEvent) string
+
+// SubjectByCategory maps events to <category>.<type>, where the category is the part of the stream id before the last
+// dot, e.g. com.hmbradley.deposit.plan.<id> belongs to com.hmbradley.deposit.plan, or before the first dash for stream
+// ids without dots, e.g. plan-1234 belongs to plan. The category is trimmed from the type when the type starts with it,
+// so the PlanCreated events of plans map to com.hmbradley.deposit.plan.PlanCreated.
+func SubjectByCategory(event *RecordedEvent) string {
+	category, _, _ := strings.Cut(event.StreamId, "-")
+	if i := strings.LastIndex(event.StreamId, "."); i >= 0 {
+		category = event.StreamId[:i]
+	}
+	eventType := strings.TrimPrefix(event.Type, category+".")
+	return subjectToken(category) + "." + subjectToken(eventType)
+}
+
+// SubjectByMessageType maps events to their type, e.g. com.hmbradley.deposit.plan.PlanCreated.
+func SubjectByMessageType(event *RecordedEvent) string {
+	return subjectToken(event.Type)
+}
+
+func subjectToken(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '*', '>':
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+type ForwarderConfig struct {
+	// Stream is the name of the JetStream stream the events are published to.
+	Stream string
+	// Prefix is the subject prefix of every event, the stream captures <Prefix>.>. Defaults to events.
+	Prefix string
+	// Subject maps the events to their subject after the prefix. Defaults to SubjectByCategory.
+	Subject SubjectMapper
+	// IncludeSystemEvents forwards the events whose stream or type starts with $, which are skipped by default.
+	IncludeSystemEvents bool
+	// Logger reports every forwarded event. Defaults to slog.Default.
+	Logger *slog.Logger
+	// Metrics records the events processed, and the lag when the EventSource is an EventCounter. Optional.
+	Metrics *onepiecemetrics.Metrics
+	// Subscription names the Forwarder in the metrics. Defaults to the stream.
+	Subscription string
+	// LagInterval is the minimum time between two lag measures. Defaults to 10 seconds.
+	LagInterval time.Duration
+}
+
+// Forwarder publishes the events of an EventSource to JetStream, in order and once: every message is deduplicated by
+// its event id, and a restarted Forwarder resumes after the position of the last message of the stream.
+type Forwarder struct {
+	js     jetstream.JetStream
+	source EventSource
+	config ForwarderConfig
+	logger *slog.Logger
+	// lagMeasuredAt is only used by forward, which the EventSource calls sequentially.
+	lagMeasuredAt time.Time
+}
+
+func NewForwarder(js jetstream.JetStream, source EventSource, config ForwarderConfig) (*Forwarder, error) {
+	if config.Stream == "" {
+		return nil, ErrForwarderStreamRequired
+	}
+	if config.Prefix == "" {
+		config.Prefix = defaultForwarderPrefix
+	}
+	if config.Subject == nil {
+		config.Subject = SubjectByCategory
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+	if config.Subscription == "" {
+		config.Subscription = config.Stream
+	}
+	if config.LagInterval == 0 {
+		config.LagInterval = defaultLagInterval
+	}
+
+	return &Forwarder{
+		js:     js,
+		source: source,
+		config: config,
+		logger: config.Logger.With(slog.String("stream", config.Stream)),
+	}, nil
+}
+
+// Run forwards the events until the context is done, which is not reported as an error. Any failure stops the
+// Forwarder, so running it again resumes from the last published event.
+func (f *Forwarder) Run(ctx context.Context) error {
+	stream, err := f.CreateStream(ctx)
+	if err != nil {
+		return err
+	}
+
+	from, err := lastPosition(ctx, stream)
+	if err != nil {
+		return err
+	}
+	if from != nil {
+		f.logger.InfoContext(ctx, "resuming forwarder", slog.Uint64("commit_position", from.Commit))
+	} else {
+		f.logger.InfoContext(ctx, "starting forwarder")
+	}
+
+	err = f.source.Subscribe(ctx, from, f.forward)
+	if ctx.Err() != nil {
+		f.logger.InfoContext(ctx, "stopped forwarder")
+		return nil
+	}
+	return err
+}
+
+// CreateStream creates or updates the JetStream stream capturing the subjects of the Forwarder.
+func (f *Forwarder) CreateStream(ctx context.Context) (jetstream.Stream, error) {
+	return f.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     f.config.Stream,
+		Subjects: []string{f.config.Prefix + ".>"},
+	})
+}
+
+func (f *Forwarder) forward(ctx context.Context, event *RecordedEvent) error {
+	if !f.config.IncludeSystemEvents && (strings.HasPrefix(event.StreamId, "$") || strings.HasPrefix(event.Type, "$")) {
+		return nil
+	}
+
+	err := f.Publish(ctx, event)
+	if f.config.Metrics != nil {
+		f.config.Metrics.SubscriptionProcessed(f.config.Subscription, err)
+		if err == nil {
+			f.measureLag(ctx, event.Position)
+		}
+	}
+	return err
+}
+
+// measureLag records the number of events after the position, at most once per LagInterval.
+func (f *Forwarder) measureLag(ctx context.Context, position EventPosition) {
+	counter, ok := f.source.(EventCounter)
+	if !ok || time.Since(f.lagMeasuredAt) < f.config.LagInterval {
+		return
+	}
+	f.lagMeasuredAt = time.Now()
+
+	lag, err := counter.CountAfter(ctx, position)
+	if err != nil {
+		f.logger.WarnContext(ctx, "measuring the forwarder lag failed", slog.Any("error", err))
+		return
+	}
+	f.config.Metrics.SubscriptionLag(f.config.Subscription, lag)
+}
+
+// Publish publishes a single event to the stream, deduplicated by its event id. An event with malformed metadata is
+// published without its trace context and correlation ids, since it would otherwise stop the Forwarder for good.
+func (f *Forwarder) Publish(ctx context.Context, event *RecordedEvent) error {
+	subject := f.config.Prefix + "." + f.config.Subject(event)
+	logger := f.logger.With(
+		slog.String(eventsourcing.LogKeyStreamId, event.StreamId),
+		slog.String("event_type", event.Type),
+		slog.String("event_id", event.Id),
+		slog.Uint64(eventsourcing.LogKeyRevision, event.Revision),
+		slog.String("subject", subject),
+	)
+
+	metadata := eventsourcing.Metadata{}
+	if len(event.Metadata) > 0 {
+		if err := json.Unmarshal(event.Metadata, &metadata); err != nil {
+			logger.WarnContext(ctx, "malformed event metadata", slog.Any("error", err))
+			metadata = eventsourcing.Metadata{}
+		}
+	}
+	carrier := eventsourcing.MetadataCarrier(metadata)
+
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	ctx, span := tracer.Start(ctx, subject,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("onepiece.stream_id", event.StreamId),
+			attribute.String("onepiece.event_type", event.Type),
+		),
+	)
+	defer span.End()
+
+	msg := nats.NewMsg(subject)
+	msg.Data = event.Data
+	msg.Header.Set(HeaderEventId, event.Id)
+	msg.Header.Set(HeaderEventType, event.Type)
+	msg.Header.Set(HeaderStreamId, event.StreamId)
+	msg.Header.Set(HeaderEventRevision, strconv.FormatUint(event.Revision, 10))
+	msg.Header.Set(HeaderCommitPosition, strconv.FormatUint(event.Position.Commit, 10))
+	msg.Header.Set(HeaderPreparePosition, strconv.FormatUint(event.Position.Prepare, 10))
+	if event.ContentType != "" {
+		msg.Header.Set(HeaderContentType, event.ContentType)
+	}
+	if id := carrier.Get("$correlationId"); id != "" {
+		msg.Header.Set(HeaderCorrelationId, id)
+	}
+	if id := carrier.Get("$causationId"); id != "" {
+		msg.Header.Set(HeaderCausationId, id)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier(msg.Header))
+
+	if _, err := f.js.PublishMsg(ctx, msg, jetstream.WithMsgID(event.Id)); err != nil {
+		span.RecordError(err)
+		logger.ErrorContext(ctx, "event forwarding failed", slog.Any("error", err))
+		return err
+	}
+
+	logger.DebugContext(ctx, "event forwarded")
+	return nil
+}
+
+// lastPosition reads the position of the last event published to the stream.
+func lastPosition(ctx context.Context, stream jetstream.Stream) (*EventPosition, error) {
+	info, err := stream.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.State.LastSeq == 0 {
+		return nil, nil
+	}
+
+	msg, err := stream.GetMsg(ctx, info.State.LastSeq)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := strconv.ParseUint(msg.Header.Get(HeaderCommitPosition), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid position of the last message %d: %w", msg.Sequence, err)
+	}
+	prepare, err := strconv.ParseUint(msg.Header.Get(HeaderPreparePosition), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid position of the last message %d: %w", msg.Sequence, err)
+	}
+	return &EventPosition{Commit: commit, Prepare: prepare}, nil
+}
+
+// EventStoreSource streams the $all stream of EventStoreDB with a catch-up subscription.
+type EventStoreSource struct {
+	db *esdb.Client
+}
+
+func NewEventStoreSource(db *esdb.Client) *EventStoreSource {
+	return &EventStoreSource{db: db}
+}
+
+func (s *EventStoreSource) Subscribe(
+	ctx context.Context,
+	from *EventPosition,
+	handle func(ctx context.Context, event *RecordedEvent) error,
+) error {
+	var start esdb.AllPosition = esdb.Start{}
+	if from != nil {
+		start = esdb.Position{Commit: from.Commit, Prepare: from.Prepare}
+	}
+
+	sub, err := s.db.SubscribeToAll(ctx, esdb.SubscribeToAllOptions{
+		From:   start,
+		Filter: esdb.ExcludeSystemEventsFilter(),
+	})
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	for {
+		event := sub.Recv()
+		if event.SubscriptionDropped != nil {
+			return event.SubscriptionDropped.Error
+		}
+		if event.EventAppeared == nil {
+			continue
+		}
+
+		ev := event.EventAppeared.OriginalEvent()
+		err := handle(ctx, &RecordedEvent{
+			Id:          ev.EventID.String(),
+			StreamId:    ev.StreamID,
+			Type:        ev.EventType,
+			Revision:    ev.EventNumber,
+			Position:    EventPosition{Commit: ev.Position.Commit, Prepare: ev.Position.Prepare},
+			ContentType: ev.ContentType,
+			Data:        ev.Data,
+			Metadata:    ev.UserMetadata,
+			CreatedAt:   ev.CreatedDate,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// CountAfter counts the events after the position, up to 10000, skipping the system events.
+func (s *EventStoreSource) CountAfter(ctx context.Context, from EventPosition) (uint64, error) {
+	stream, err := s.db.ReadAll(ctx, esdb.ReadAllOptions{
+		From:      esdb.Position{Commit: from.Commit, Prepare: from.Prepare},
+		Direction: esdb.Forwards,
+	}, maxCountedEvents+1)
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+
+	var count uint64
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		ev := event.OriginalEvent()
+		if ev.Position.Commit == from.Commit && ev.Position.Prepare == from.Prepare {
+			continue
+		}
+		if strings.HasPrefix(ev.StreamID, "$") || strings.HasPrefix(ev.EventType, "$") {
+			continue
+		}
+		count++
+	}
+}
@@ -0,0 +1,258 @@
+package golang_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecemetrics"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	golang "unstable"
+	"unstable/plandomain/planproto"
+	"unstable/planinfra"
+)
+
+// sliceSource replays its events and waits for the context to be done, like a live subscription.
+type sliceSource struct {
+	mu     sync.Mutex
+	events []*golang.RecordedEvent
+	froms  []*golang.EventPosition
+}
+
+func (s *sliceSource) Subscribe(
+	ctx context.Context,
+	from *golang.EventPosition,
+	handle func(ctx context.Context, event *golang.RecordedEvent) error,
+) error {
+	s.mu.Lock()
+	s.froms = append(s.froms, from)
+	events := s.events
+	s.mu.Unlock()
+
+	for _, event := range events {
+		if from != nil && event.Position.Commit <= from.Commit {
+			continue
+		}
+		if err := handle(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// CountAfter counts the non-system events after the position.
+func (s *sliceSource) CountAfter(_ context.Context, from golang.EventPosition) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count uint64
+	for _, event := range s.events {
+		if event.Position.Commit > from.Commit && !strings.HasPrefix(event.Type, "$") {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func recordedEvent(id string, streamId string, eventType string, commit uint64) *golang.RecordedEvent {
+	return &golang.RecordedEvent{
+		Id:          id,
+		StreamId:    streamId,
+		Type:        eventType,
+		Position:    golang.EventPosition{Commit: commit, Prepare: commit},
+		ContentType: "application/octet-stream",
+		Data:        []byte(id),
+		Metadata:    []byte(`{"$correlationId": "correlation-` + id + `"}`),
+	}
+}
+
+func TestForwarder(t *testing.T) {
+	nc := runNats(t)
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+
+	source := &sliceSource{events: []*golang.RecordedEvent{
+		recordedEvent("e1", "plan-1", "com.hmbradley.deposit.plan.PlanCreated", 10),
+		recordedEvent("e2", "$stats-1", "$statsCollected", 20),
+		recordedEvent("e3", "plan-1", "com.hmbradley.deposit.plan.PlanArchived", 30),
+	}}
+
+	forwarder, err := golang.NewForwarder(js, source, golang.ForwarderConfig{Stream: "EVENTS"})
+	require.NoError(t, err)
+
+	run := func(t *testing.T, wantMsgs uint64) {
+		t.Helper()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- forwarder.Run(ctx) }()
+
+		require.Eventually(t, func() bool {
+			stream, err := js.Stream(context.Background(), "EVENTS")
+			if err != nil {
+				return false
+			}
+			info, err := stream.Info(context.Background())
+			return err == nil && info.State.Msgs == wantMsgs
+		}, 5*time.Second, 10*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+	}
+
+	t.Run("forwards events with their headers", func(t *testing.T) {
+		run(t, 2)
+
+		stream, err := js.Stream(context.Background(), "EVENTS")
+		require.NoError(t, err)
+
+		msg, err := stream.GetMsg(context.Background(), 1)
+		require.NoError(t, err)
+		require.Equal(t, "events.plan.com.hmbradley.deposit.plan.PlanCreated", msg.Subject)
+		require.Equal(t, []byte("e1"), msg.Data)
+		require.Equal(t, "e1", msg.Header.Get(golang.HeaderEventId))
+		require.Equal(t, "plan-1", msg.Header.Get(golang.HeaderStreamId))
+		require.Equal(t, "10", msg.Header.Get(golang.HeaderCommitPosition))
+		require.Equal(t, "correlation-e1", msg.Header.Get(golang.HeaderCorrelationId))
+		require.Equal(t, "application/octet-stream", msg.Header.Get(golang.HeaderContentType))
+
+		msg, err = stream.GetMsg(context.Background(), 2)
+		require.NoError(t, err)
+		require.Equal(t, "e3", msg.Header.Get(golang.HeaderEventId))
+	})
+
+	t.Run("resumes after the last published event", func(t *testing.T) {
+		source.mu.Lock()
+		source.events = append(source.events, recordedEvent("e4", "plan-2", "com.hmbradley.deposit.plan.PlanCreated", 40))
+		source.mu.Unlock()
+
+		run(t, 3)
+
+		source.mu.Lock()
+		defer source.mu.Unlock()
+		require.Equal(t, []*golang.EventPosition{nil, {Commit: 30, Prepare: 30}}, source.froms)
+	})
+
+	t.Run("deduplicates events by id", func(t *testing.T) {
+		stream, err := js.Stream(context.Background(), "EVENTS")
+		require.NoError(t, err)
+
+		ack, err := js.Publish(context.Background(), "events.plan.duplicate", nil, jetstream.WithMsgID("e4"))
+		require.NoError(t, err)
+		require.True(t, ack.Duplicate)
+
+		info, err := stream.Info(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), info.State.Msgs)
+	})
+
+	t.Run("maps subjects by message type", func(t *testing.T) {
+		require.Equal(t, "com.hmbradley.deposit.plan.PlanCreated", golang.SubjectByMessageType(source.events[0]))
+	})
+}
+
+func TestForwarderMetrics(t *testing.T) {
+	nc := runNats(t)
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+
+	registry := prometheus.NewRegistry()
+	source := &sliceSource{events: []*golang.RecordedEvent{
+		recordedEvent("e1", "plan-1", "com.hmbradley.deposit.plan.PlanCreated", 10),
+		recordedEvent("e2", "$stats-1", "$statsCollected", 20),
+		recordedEvent("e3", "plan-1", "com.hmbradley.deposit.plan.PlanArchived", 30),
+	}}
+
+	forwarder, err := golang.NewForwarder(js, source, golang.ForwarderConfig{
+		Stream:       "EVENTS",
+		Metrics:      onepiecemetrics.MustNewMetrics(registry),
+		Subscription: "nats-sink",
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- forwarder.Run(ctx) }()
+
+	// NOTE: the lag is measured once per interval, so only after the first event, with one more event to forward.
+	expected := `
+# HELP onepiece_subscription_processed_total Number of events processed by a subscription, by outcome.
+# TYPE onepiece_subscription_processed_total counter
+onepiece_subscription_processed_total{outcome="success",subscription="nats-sink"} 2
+# HELP onepiece_subscription_lag_events Number of events a subscription is behind the head of its source.
+# TYPE onepiece_subscription_lag_events gauge
+onepiece_subscription_lag_events{subscription="nats-sink"} 1
+`
+	require.Eventually(t, func() bool {
+		return testutil.GatherAndCompare(
+			registry,
+			strings.NewReader(expected),
+			"onepiece_subscription_processed_total",
+			"onepiece_subscription_lag_events",
+		) == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestForwarderMalformedMetadata(t *testing.T) {
+	nc := runNats(t)
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+
+	event := recordedEvent("e1", "plan-1", "com.hmbradley.deposit.plan.PlanCreated", 10)
+	event.Metadata = []byte(`{"$correlationId":`)
+	source := &sliceSource{events: []*golang.RecordedEvent{event}}
+
+	var logs bytes.Buffer
+	forwarder, err := golang.NewForwarder(js, source, golang.ForwarderConfig{
+		Stream: "EVENTS",
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- forwarder.Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		stream, err := js.Stream(context.Background(), "EVENTS")
+		if err != nil {
+			return false
+		}
+		info, err := stream.Info(context.Background())
+		return err == nil && info.State.Msgs == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	require.Contains(t, logs.String(), `msg="malformed event metadata"`)
+	require.Contains(t, logs.String(), "event_id=e1")
+}
+
+func TestSubjectByCategory(t *testing.T) {
+	store := onepiecetesting.NewMemoryEventStore()
+	for _, planId := range []string{"d83a3744-0e53-4fb7-88f7-7ffc831f0090", "5f0c2a38-8f5e-4d0c-9a44-6bb1f4e3c2a1"} {
+		_, err := planinfra.DispatchCommand(context.Background(), store, &planproto.Command{
+			Command: &planproto.Command_CreatePlan{CreatePlan: &planproto.CreatePlan{PlanId: planId, Title: "Vacation"}},
+		}, nil)
+		require.NoError(t, err)
+	}
+
+	for _, stored := range store.Events() {
+		event := &golang.RecordedEvent{StreamId: stored.StreamId, Type: stored.EventType}
+		require.Equal(t, "com.hmbradley.deposit.plan.PlanCreated", golang.SubjectByCategory(event), stored.StreamId)
+	}
+
+	require.Equal(t, "plan.PlanCreated", golang.SubjectByCategory(&golang.RecordedEvent{StreamId: "plan-1", Type: "PlanCreated"}))
+}
@@ -0,0 +1,79 @@
+package golang
+
+import (
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"strings"
+)
+
+// Headers mapped into the eventsourcing.Options of a command. Every header starting with HeaderMetadataPrefix becomes
+// a metadata entry named after the rest of the header, e.g. Onepiece-Metadata-User-Id sets the User-Id metadata.
+const (
+	HeaderExpectedRevision = "Onepiece-Expected-Revision"
+	HeaderCorrelationId    = "Onepiece-Correlation-Id"
+	HeaderCausationId      = "Onepiece-Causation-Id"
+	HeaderMetadataPrefix   = "Onepiece-Metadata-"
+)
+
+// OptionsFromHeaders reads the eventsourcing.Options of a command from the request headers. Missing headers leave the
+// matching option unset, so the CommandHandler falls back to its defaults.
+func OptionsFromHeaders(header nats.Header) (*eventsourcing.Options, error) {
+	opts := &eventsourcing.Options{}
+
+	if value := header.Get(HeaderExpectedRevision); value != "" {
+		revision, err := eventsourcing.ParseExpectedRevision(value)
+		if err != nil {
+			return nil, err
+		}
+		opts.ExpectedRevision = revision
+	}
+	if value := header.Get(HeaderCorrelationId); value != "" {
+		id := eventsourcing.CorrelationId(value)
+		opts.CorrelationId = &id
+	}
+	if value := header.Get(HeaderCausationId); value != "" {
+		id := eventsourcing.CausationId(value)
+		opts.CausationId = &id
+	}
+
+	for key, values := range header {
+		name, ok := strings.CutPrefix(key, HeaderMetadataPrefix)
+		if !ok || name == "" || len(values) == 0 {
+			continue
+		}
+		if opts.Metadata == nil {
+			opts.Metadata = make(eventsourcing.Metadata)
+		}
+		opts.Metadata[name] = values[0]
+	}
+
+	return opts, nil
+}
+
+// SetOptionsHeaders writes the eventsourcing.Options of a command into the request headers, the inverse of
+// OptionsFromHeaders. Metadata values are formatted with fmt.Sprint.
+func SetOptionsHeaders(header nats.Header, opts *eventsourcing.Options) error {
+	if opts == nil {
+		return nil
+	}
+
+	if opts.ExpectedRevision != nil {
+		value, err := eventsourcing.FormatExpectedRevision(opts.ExpectedRevision)
+		if err != nil {
+			return err
+		}
+		header.Set(HeaderExpectedRevision, value)
+	}
+	if opts.CorrelationId != nil {
+		header.Set(HeaderCorrelationId, string(*opts.CorrelationId))
+	}
+	if opts.CausationId != nil {
+		header.Set(HeaderCausationId, string(*opts.CausationId))
+	}
+	for name, value := range opts.Metadata {
+		header.Set(HeaderMetadataPrefix+name, fmt.Sprint(value))
+	}
+
+	return nil
+}
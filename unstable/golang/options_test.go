@@ -0,0 +1,44 @@
+package golang_test
+
+import (
+	"github.com/nats-io/nats.go"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/stretchr/testify/require"
+	"testing"
+	golang "unstable"
+)
+
+func TestOptionsHeaders(t *testing.T) {
+	correlationId := eventsourcing.CorrelationId("correlation")
+	causationId := eventsourcing.CausationId("causation")
+
+	tests := []struct {
+		name string
+		opts *eventsourcing.Options
+	}{
+		{name: "empty", opts: &eventsourcing.Options{}},
+		{name: "any", opts: &eventsourcing.Options{ExpectedRevision: eventsourcing.Any{}}},
+		{name: "no stream", opts: &eventsourcing.Options{ExpectedRevision: eventsourcing.NoStream{}}},
+		{name: "stream exists", opts: &eventsourcing.Options{ExpectedRevision: eventsourcing.StreamExists{}}},
+		{
+			name: "everything",
+			opts: &eventsourcing.Options{
+				ExpectedRevision: eventsourcing.Revision(42),
+				Metadata:         eventsourcing.Metadata{"Tenant": "acme"},
+				CorrelationId:    &correlationId,
+				CausationId:      &causationId,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := nats.Header{}
+			require.NoError(t, golang.SetOptionsHeaders(header, tt.opts))
+
+			opts, err := golang.OptionsFromHeaders(header)
+			require.NoError(t, err)
+			require.Equal(t, tt.opts, opts)
+		})
+	}
+}
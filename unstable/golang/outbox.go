@@ -0,0 +1,40 @@
+package golang
+
+import (
+	"context"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecesql"
+)
+
+// OutboxPublisher publishes the outbox of a onepiecesql.Store with the subjects and headers of a Forwarder, so the
+// events of a SQL event store reach JetStream like the ones forwarded from EventStoreDB. The commit position of the
+// messages is their outbox id.
+type OutboxPublisher struct {
+	forwarder *Forwarder
+}
+
+func NewOutboxPublisher(forwarder *Forwarder) *OutboxPublisher {
+	return &OutboxPublisher{forwarder: forwarder}
+}
+
+func (p *OutboxPublisher) Publish(ctx context.Context, message *onepiecesql.OutboxMessage) error {
+	position := uint64(message.Id)
+	return p.forwarder.Publish(ctx, &RecordedEvent{
+		Id:          message.Event.EventId,
+		StreamId:    message.StreamId,
+		Type:        message.Event.EventType,
+		Revision:    message.Event.Revision,
+		Position:    EventPosition{Commit: position, Prepare: position},
+		ContentType: contentTypeName(message.ContentType),
+		Data:        message.Event.Data,
+		Metadata:    message.Event.Metadata,
+		CreatedAt:   message.CreatedAt,
+	})
+}
+
+func contentTypeName(contentType eventsourcing.ContentType) string {
+	if contentType == eventsourcing.ContentTypeJson {
+		return "application/json"
+	}
+	return "application/octet-stream"
+}
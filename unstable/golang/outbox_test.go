@@ -0,0 +1,57 @@
+package golang_test
+
+import (
+	"context"
+	"database/sql"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecesql"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+	"path/filepath"
+	"testing"
+	golang "unstable"
+	"unstable/plandomain/planproto"
+	"unstable/planinfra"
+)
+
+func TestOutboxPublisher(t *testing.T) {
+	ctx := context.Background()
+	nc := runNats(t)
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "events.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	store := onepiecesql.NewStore(db, onepiecesql.SQLite)
+	require.NoError(t, store.Migrate(ctx))
+
+	forwarder, err := golang.NewForwarder(js, nil, golang.ForwarderConfig{Stream: "EVENTS"})
+	require.NoError(t, err)
+	stream, err := forwarder.CreateStream(ctx)
+	require.NoError(t, err)
+
+	correlationId := eventsourcing.CorrelationId("correlation")
+	_, err = planinfra.DispatchCommand(ctx, store, &planproto.Command{Command: &planproto.Command_CreatePlan{CreatePlan: &planproto.CreatePlan{
+		PlanId: "d83a3744-0e53-4fb7-88f7-7ffc831f0090",
+		Title:  "Vacation",
+	}}}, &eventsourcing.Options{CorrelationId: &correlationId})
+	require.NoError(t, err)
+
+	relay := onepiecesql.NewRelay(db, onepiecesql.SQLite, golang.NewOutboxPublisher(forwarder), onepiecesql.RelayConfig{})
+	sent, err := relay.RelayPending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, sent)
+
+	msg, err := stream.GetMsg(ctx, 1)
+	require.NoError(t, err)
+	recorded, err := store.ReadStream(ctx, msg.Header.Get(golang.HeaderStreamId))
+	require.NoError(t, err)
+	require.Len(t, recorded, 1)
+	require.Equal(t, recorded[0].EventId, msg.Header.Get(golang.HeaderEventId))
+	require.Equal(t, recorded[0].EventType, msg.Header.Get(golang.HeaderEventType))
+	require.Equal(t, "application/json", msg.Header.Get(golang.HeaderContentType))
+	require.Equal(t, "correlation", msg.Header.Get(golang.HeaderCorrelationId))
+	require.Equal(t, "1", msg.Header.Get(golang.HeaderCommitPosition))
+}
@@ -1,12 +1,12 @@
 package createplan
 
 import (
-	"errors"
 	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"google.golang.org/protobuf/proto"
 	"unstable/plandomain/planproto"
 )
 
-var ErrPlanExists = errors.New("plan already exists")
+var ErrPlanExists = onepiece.NewDomainError("plan_exists", onepiece.StatusAlreadyExists, "plan already exists")
 
 var Decider = onepiece.NewDecider(decide, evolve)
 
@@ -40,7 +40,7 @@ func decide(state State, command *planproto.CreatePlan) ([]*planproto.Event, err
 func evolve(state State, event *planproto.Event) State {
 	switch e := event.Event.(type) {
 	case *planproto.Event_PlanCreated:
-		state.PlanId = &e.PlanCreated.PlanId
+		state.PlanId = proto.String(e.PlanCreated.PlanId)
 		return state
 	default:
 		return state
@@ -1,14 +1,14 @@
 package drainplan
 
 import (
-	"errors"
 	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"google.golang.org/protobuf/proto"
 	"unstable/plandomain/planproto"
 )
 
-var ErrPlanNotFound = errors.New("plan not found")
-var ErrPlanUnarchived = errors.New("plan must be archived")
-var ErrPlanDrained = errors.New("plan already drained")
+var ErrPlanNotFound = onepiece.NewDomainError("plan_not_found", onepiece.StatusNotFound, "plan not found")
+var ErrPlanUnarchived = onepiece.NewDomainError("plan_unarchived", onepiece.StatusFailedPrecondition, "plan must be archived")
+var ErrPlanDrained = onepiece.NewDomainError("plan_drained", onepiece.StatusFailedPrecondition, "plan already drained")
 
 var Decider = onepiece.NewDecider(decide, evolve)
 
@@ -45,7 +45,7 @@ func decide(state State, command *planproto.DrainPlan) ([]*planproto.Event, erro
 func evolve(state State, event *planproto.Event) State {
 	switch e := event.Event.(type) {
 	case *planproto.Event_PlanCreated:
-		state.PlanId = &e.PlanCreated.PlanId
+		state.PlanId = proto.String(e.PlanCreated.PlanId)
 		return state
 	case *planproto.Event_PlanArchived:
 		state.IsArchived = true
@@ -1,13 +1,13 @@
 package updateplan
 
 import (
-	"errors"
 	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"google.golang.org/protobuf/proto"
 	"unstable/plandomain/planproto"
 )
 
-var ErrPlanNotFound = errors.New("plan not found")
-var ErrPlanArchived = errors.New("plan already archived")
+var ErrPlanNotFound = onepiece.NewDomainError("plan_not_found", onepiece.StatusNotFound, "plan not found")
+var ErrPlanArchived = onepiece.NewDomainError("plan_archived", onepiece.StatusFailedPrecondition, "plan already archived")
 
 var Decider = onepiece.NewDecider(decide, evolve)
 
@@ -44,7 +44,7 @@ func decide(state State, command *planproto.UpdatePlan) ([]*planproto.Event, err
 func evolve(state State, event *planproto.Event) State {
 	switch e := event.Event.(type) {
 	case *planproto.Event_PlanCreated:
-		state.PlanId = &e.PlanCreated.PlanId
+		state.PlanId = proto.String(e.PlanCreated.PlanId)
 		return state
 	case *planproto.Event_PlanArchived:
 		state.IsArchived = true
@@ -1,8 +1,8 @@
 package planactor
 
 import (
-	"errors"
 	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"google.golang.org/protobuf/proto"
 	"unstable/plandomain/commands/archiveplan"
 	"unstable/plandomain/commands/createplan"
 	"unstable/plandomain/commands/drainplan"
@@ -11,51 +11,52 @@ import (
 	"unstable/plandomain/planproto"
 )
 
-var ErrPlanExists = errors.New("plan already exists")
-var ErrPlanNotFound = errors.New("plan not found")
-var ErrPlanArchived = errors.New("plan already archived")
-var ErrPlanUnarchived = errors.New("plan must be archived")
-var ErrPlanDrained = errors.New("plan already drained")
+var ErrPlanExists = onepiece.NewDomainError("plan_exists", onepiece.StatusAlreadyExists, "plan already exists")
+var ErrPlanNotFound = onepiece.NewDomainError("plan_not_found", onepiece.StatusNotFound, "plan not found")
+var ErrPlanArchived = onepiece.NewDomainError("plan_archived", onepiece.StatusFailedPrecondition, "plan already archived")
+var ErrPlanUnarchived = onepiece.NewDomainError("plan_unarchived", onepiece.StatusFailedPrecondition, "plan must be archived")
+var ErrPlanDrained = onepiece.NewDomainError("plan_drained", onepiece.StatusFailedPrecondition, "plan already drained")
 
 var Decider = onepiece.NewDecider(decide, evolve)
 
-type state struct {
-	planId     *string
-	isArchived bool
-	isDrained  bool
+// State is the state of a plan, shared by the deciders of its commands.
+type State struct {
+	PlanId     *string
+	IsArchived bool
+	IsDrained  bool
 }
 
-func decide(state state, command *planproto.Command) ([]*planproto.Event, error) {
+func decide(state State, command *planproto.Command) ([]*planproto.Event, error) {
 	switch c := command.Command.(type) {
 	case *planproto.Command_CreatePlan:
 		return createplan.Decider.Decide(createplan.State{
-			PlanId: state.planId,
+			PlanId: state.PlanId,
 		}, c.CreatePlan)
 
 	case *planproto.Command_ArchivePlan:
 		return archiveplan.Decider.Decide(archiveplan.State{
-			PlanId:     state.planId,
-			IsArchived: state.isArchived,
+			PlanId:     state.PlanId,
+			IsArchived: state.IsArchived,
 		}, c.ArchivePlan)
 
 	case *planproto.Command_UpdatePlan:
 		return updateplan.Decider.Decide(updateplan.State{
-			PlanId:     state.planId,
-			IsArchived: state.isArchived,
+			PlanId:     state.PlanId,
+			IsArchived: state.IsArchived,
 		}, c.UpdatePlan)
 
 	case *planproto.Command_DrainPlan:
 		return drainplan.Decider.Decide(drainplan.State{
-			PlanId:     state.planId,
-			IsArchived: state.isArchived,
-			IsDrained:  state.isDrained,
+			PlanId:     state.PlanId,
+			IsArchived: state.IsArchived,
+			IsDrained:  state.IsDrained,
 		}, c.DrainPlan)
 
 	case *planproto.Command_FailDrainPlan:
 		return faildrainplan.Decider.Decide(faildrainplan.State{
-			PlanId:     state.planId,
-			IsArchived: state.isArchived,
-			IsDrained:  state.isDrained,
+			PlanId:     state.PlanId,
+			IsArchived: state.IsArchived,
+			IsDrained:  state.IsDrained,
 		}, c.FailDrainPlan)
 
 	default:
@@ -63,16 +64,16 @@ func decide(state state, command *planproto.Command) ([]*planproto.Event, error)
 	}
 }
 
-func evolve(state state, event *planproto.Event) state {
+func evolve(state State, event *planproto.Event) State {
 	switch e := event.Event.(type) {
 	case *planproto.Event_PlanCreated:
-		state.planId = &e.PlanCreated.PlanId
+		state.PlanId = proto.String(e.PlanCreated.PlanId)
 		return state
 	case *planproto.Event_PlanArchived:
-		state.isArchived = true
+		state.IsArchived = true
 		return state
 	case *planproto.Event_PlanDrained:
-		state.isDrained = true
+		state.IsDrained = true
 		return state
 	default:
 		return state
@@ -170,6 +170,26 @@ func TestDrainPlan(t *testing.T) {
 			}}}).Assert()
 	})
 
+	t.Run("drains a plan with the transfer of the command", func(t *testing.T) {
+		onepiecetesting.NewTestCase(t, planactor.Decider).
+			Given(&planproto.Event{Event: &planproto.Event_PlanCreated{PlanCreated: &planproto.PlanCreated{
+				PlanId: "d83a3744-0e53-4fb7-88f7-7ffc831f0090",
+			}}},
+				&planproto.Event{Event: &planproto.Event_PlanArchived{PlanArchived: &planproto.PlanArchived{
+					PlanId: "d83a3744-0e53-4fb7-88f7-7ffc831f0090",
+				}}},
+			).
+			When(&planproto.Command{Command: &planproto.Command_DrainPlan{DrainPlan: &planproto.DrainPlan{
+				PlanId:     "d83a3744-0e53-4fb7-88f7-7ffc831f0090",
+				TransferId: "f748aac4-36a7-4c2f-a72c-e063e7462ce5",
+				DrainedAt:  timestamppb.Now(),
+			}}}).
+			ThenMatching(onepiecetesting.Partial(&planproto.PlanDrained{
+				PlanId:     "d83a3744-0e53-4fb7-88f7-7ffc831f0090",
+				TransferId: "f748aac4-36a7-4c2f-a72c-e063e7462ce5",
+			})).Assert()
+	})
+
 	t.Run("fails to drain a plan if the plan is already drained", func(t *testing.T) {
 		onepiecetesting.NewTestCase(t, planactor.Decider).
 			Given(
@@ -0,0 +1,46 @@
+package plandomain_test
+
+import (
+	"errors"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"testing"
+	"unstable/plandomain/planactor"
+	"unstable/plandomain/planproto"
+)
+
+type planInvariant = onepiecetesting.Invariant[planactor.State, *planproto.Command, *planproto.Event]
+type planTransition = onepiecetesting.Transition[planactor.State, *planproto.Command, *planproto.Event]
+
+func TestPlanProperties(t *testing.T) {
+	commands, err := onepiecetesting.ProtoGenerator[*planproto.Command](onepiecetesting.ProtoGeneratorConfig{
+		Strings: map[protoreflect.Name][]string{"planId": {"d83a3744-0e53-4fb7-88f7-7ffc831f0090"}},
+	})
+	require.NoError(t, err)
+
+	onepiecetesting.CheckProperties(t, planactor.Decider, onepiecetesting.PropertyConfig[*planproto.Command]{
+		Commands: commands,
+		Runs:     500,
+		Seed:     1,
+	},
+		planInvariant{
+			Name: "a drained plan is always archived",
+			Check: func(transition planTransition) error {
+				if transition.After.IsDrained && !transition.After.IsArchived {
+					return errors.New("drained plan is not archived")
+				}
+				return nil
+			},
+		},
+		planInvariant{
+			Name: "a plan is created once",
+			Check: func(transition planTransition) error {
+				if transition.Before.PlanId != nil && len(transition.Events) > 0 && transition.Events[0].GetPlanCreated() != nil {
+					return errors.New("existing plan created again")
+				}
+				return nil
+			},
+		},
+	)
+}
@@ -0,0 +1,31 @@
+package plandomain_test
+
+import (
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"testing"
+	"unstable/plandomain/planactor"
+	"unstable/plandomain/planproto"
+)
+
+func TestPlanPurity(t *testing.T) {
+	planId := "d83a3744-0e53-4fb7-88f7-7ffc831f0090"
+
+	onepiecetesting.AssertPure(t, planactor.Decider, nil,
+		&planproto.Command{Command: &planproto.Command_CreatePlan{CreatePlan: &planproto.CreatePlan{PlanId: planId, Title: "Vacation"}}},
+		&planproto.Command{Command: &planproto.Command_UpdatePlan{UpdatePlan: &planproto.UpdatePlan{PlanId: planId, Title: "Holidays"}}},
+		&planproto.Command{Command: &planproto.Command_ArchivePlan{ArchivePlan: &planproto.ArchivePlan{PlanId: planId}}},
+		&planproto.Command{Command: &planproto.Command_DrainPlan{DrainPlan: &planproto.DrainPlan{PlanId: planId}}},
+	)
+
+	commands, err := onepiecetesting.ProtoGenerator[*planproto.Command](onepiecetesting.ProtoGeneratorConfig{
+		Strings: map[protoreflect.Name][]string{"planId": {planId}},
+	})
+	require.NoError(t, err)
+
+	onepiecetesting.CheckProperties(t, planactor.Decider, onepiecetesting.PropertyConfig[*planproto.Command]{
+		Commands: commands,
+		Seed:     1,
+	}, onepiecetesting.PurityInvariant(planactor.Decider))
+}
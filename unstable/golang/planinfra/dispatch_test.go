@@ -0,0 +1,58 @@
+package planinfra
+
+import (
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"unstable/plandomain/planactor"
+	"unstable/plandomain/planproto"
+)
+
+func TestDispatchCommand(t *testing.T) {
+	planId := "d83a3744-0e53-4fb7-88f7-7ffc831f0090"
+	drain := &planproto.Command{Command: &planproto.Command_DrainPlan{DrainPlan: &planproto.DrainPlan{PlanId: planId, TransferId: "transfer-1"}}}
+	failDrain := &planproto.Command{Command: &planproto.Command_FailDrainPlan{FailDrainPlan: &planproto.FailDrainPlan{PlanId: planId, TransferId: "transfer-1"}}}
+	stream := "com.hmbradley.deposit.plan." + planId
+
+	created := &planproto.Event{Event: &planproto.Event_PlanCreated{PlanCreated: &planproto.PlanCreated{PlanId: planId, Title: "Vacation"}}}
+	archived := &planproto.Event{Event: &planproto.Event_PlanArchived{PlanArchived: &planproto.PlanArchived{PlanId: planId}}}
+	drained := &planproto.Event{Event: &planproto.Event_PlanDrained{PlanDrained: &planproto.PlanDrained{PlanId: planId, TransferId: "transfer-1"}}}
+	correlationId := eventsourcing.CorrelationId("correlation-1")
+	causationId := eventsourcing.CausationId("causation-1")
+
+	t.Run("drains an archived plan", func(t *testing.T) {
+		onepiecetesting.NewDispatchHarness(t, DispatchCommand, planCodec).
+			Seed(stream, created, archived).
+			Dispatch(drain, &eventsourcing.Options{CorrelationId: &correlationId, CausationId: &causationId}).
+			AssertNoError().
+			AssertStreamId(stream).
+			AssertEventTypes("com.hmbradley.deposit.plan.PlanDrained").
+			AssertContentType(eventsourcing.ContentTypeJson).
+			AssertCorrelationId(correlationId).
+			AssertCausationId(causationId).
+			AssertRevisions(2).
+			AssertNextExpectedVersion(2)
+	})
+
+	t.Run("rejects draining a plan twice", func(t *testing.T) {
+		harness := onepiecetesting.NewDispatchHarness(t, DispatchCommand, planCodec).Seed(stream, created, archived)
+		harness.Dispatch(drain, nil).AssertNoError()
+		harness.Dispatch(drain, nil).AssertError(planactor.ErrPlanDrained)
+		require.Len(t, harness.Store().Stream(stream), 3)
+	})
+
+	t.Run("rejects failing the drain of a drained plan", func(t *testing.T) {
+		onepiecetesting.NewDispatchHarness(t, DispatchCommand, planCodec).
+			Seed(stream, created, archived, drained).
+			Dispatch(failDrain, nil).
+			AssertError(planactor.ErrPlanDrained)
+	})
+
+	t.Run("rejects a stale expected revision", func(t *testing.T) {
+		onepiecetesting.NewDispatchHarness(t, DispatchCommand, planCodec).
+			Seed(stream, created, archived).
+			Dispatch(drain, &eventsourcing.Options{ExpectedRevision: eventsourcing.Revision(0)}).
+			AssertError(eventsourcing.ErrOptimisticConcurrency)
+	})
+}
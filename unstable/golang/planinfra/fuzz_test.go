@@ -0,0 +1,45 @@
+package planinfra
+
+import (
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecetesting"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"testing"
+	"unstable/plandomain/planactor"
+	"unstable/plandomain/planproto"
+)
+
+var planCodec = onepiecetesting.Codec[*planproto.Event]{
+	Marshal:      marshalEvent,
+	Unmarshal:    unmarshalEvent,
+	GetEventType: eventTypeProvider,
+}
+
+var planStrings = onepiecetesting.ProtoGeneratorConfig{
+	Strings: map[protoreflect.Name][]string{"planId": {"d83a3744-0e53-4fb7-88f7-7ffc831f0090", ""}},
+}
+
+func FuzzPlanDecider(f *testing.F) {
+	commands, err := onepiecetesting.ProtoGenerator[*planproto.Command](planStrings)
+	if err != nil {
+		f.Fatal(err)
+	}
+	events, err := onepiecetesting.ProtoGenerator[*planproto.Event](planStrings)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	onepiecetesting.FuzzDecider(f, planactor.Decider, onepiecetesting.FuzzConfig[*planproto.Command, *planproto.Event]{
+		Commands: commands,
+		Events:   events,
+		Codec:    &planCodec,
+	})
+}
+
+func FuzzPlanCodec(f *testing.F) {
+	events, err := onepiecetesting.ProtoGenerator[*planproto.Event](onepiecetesting.ProtoGeneratorConfig{})
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	onepiecetesting.FuzzCodec(f, events, planCodec)
+}
@@ -10,40 +10,79 @@ import (
 	"unstable/plandomain/commands/createplan"
 	"unstable/plandomain/commands/drainplan"
 	"unstable/plandomain/commands/faildrainplan"
+	"unstable/plandomain/commands/updateplan"
 	"unstable/plandomain/planproto"
 )
 
-var DispatchCreatePlan = eventsourcing.NewDecider(
-	createplan.Decider,
-	createPlanStreamID,
-	marshalEvent,
-	unmarshalEvent,
-	eventTypeProvider,
-)
+var DispatchCreatePlan = NewDispatchCreatePlan()
 
-var DispatchArchivePlan = eventsourcing.NewDecider(
-	archiveplan.Decider,
-	archivePlanStreamID,
-	marshalEvent,
-	unmarshalEvent,
-	eventTypeProvider,
-)
+// NewDispatchCreatePlan returns DispatchCreatePlan with options, such as the replay hook of onepiecemetrics.
+func NewDispatchCreatePlan(options ...eventsourcing.DeciderOption) eventsourcing.CommandHandler[*planproto.CreatePlan, *planproto.Event] {
+	return eventsourcing.NewDecider(
+		createplan.Decider,
+		createPlanStreamID,
+		marshalEvent,
+		unmarshalEvent,
+		eventTypeProvider,
+		options...,
+	)
+}
 
-var DispatchDrainPlan = eventsourcing.NewDecider(
-	drainplan.Decider,
-	drainPlanStreamID,
-	marshalEvent,
-	unmarshalEvent,
-	eventTypeProvider,
-)
+var DispatchArchivePlan = NewDispatchArchivePlan()
 
-var DispatchFailDrainPlan = eventsourcing.NewDecider(
-	faildrainplan.Decider,
-	failPlanStreamID,
-	marshalEvent,
-	unmarshalEvent,
-	eventTypeProvider,
-)
+// NewDispatchArchivePlan returns DispatchArchivePlan with options, such as the replay hook of onepiecemetrics.
+func NewDispatchArchivePlan(options ...eventsourcing.DeciderOption) eventsourcing.CommandHandler[*planproto.ArchivePlan, *planproto.Event] {
+	return eventsourcing.NewDecider(
+		archiveplan.Decider,
+		archivePlanStreamID,
+		marshalEvent,
+		unmarshalEvent,
+		eventTypeProvider,
+		options...,
+	)
+}
+
+var DispatchUpdatePlan = NewDispatchUpdatePlan()
+
+// NewDispatchUpdatePlan returns DispatchUpdatePlan with options, such as the replay hook of onepiecemetrics.
+func NewDispatchUpdatePlan(options ...eventsourcing.DeciderOption) eventsourcing.CommandHandler[*planproto.UpdatePlan, *planproto.Event] {
+	return eventsourcing.NewDecider(
+		updateplan.Decider,
+		updatePlanStreamID,
+		marshalEvent,
+		unmarshalEvent,
+		eventTypeProvider,
+		options...,
+	)
+}
+
+var DispatchDrainPlan = NewDispatchDrainPlan()
+
+// NewDispatchDrainPlan returns DispatchDrainPlan with options, such as the replay hook of onepiecemetrics.
+func NewDispatchDrainPlan(options ...eventsourcing.DeciderOption) eventsourcing.CommandHandler[*planproto.DrainPlan, *planproto.Event] {
+	return eventsourcing.NewDecider(
+		drainplan.Decider,
+		drainPlanStreamID,
+		marshalEvent,
+		unmarshalEvent,
+		eventTypeProvider,
+		options...,
+	)
+}
+
+var DispatchFailDrainPlan = NewDispatchFailDrainPlan()
+
+// NewDispatchFailDrainPlan returns DispatchFailDrainPlan with options, such as the replay hook of onepiecemetrics.
+func NewDispatchFailDrainPlan(options ...eventsourcing.DeciderOption) eventsourcing.CommandHandler[*planproto.FailDrainPlan, *planproto.Event] {
+	return eventsourcing.NewDecider(
+		faildrainplan.Decider,
+		failPlanStreamID,
+		marshalEvent,
+		unmarshalEvent,
+		eventTypeProvider,
+		options...,
+	)
+}
 
 func failPlanStreamID(command *planproto.FailDrainPlan) (string, error) {
 	return protobuf.StreamID(command, command.PlanId), nil
@@ -53,6 +92,10 @@ func drainPlanStreamID(command *planproto.DrainPlan) (string, error) {
 	return protobuf.StreamID(command, command.PlanId), nil
 }
 
+func updatePlanStreamID(command *planproto.UpdatePlan) (string, error) {
+	return protobuf.StreamID(command, command.PlanId), nil
+}
+
 func archivePlanStreamID(command *planproto.ArchivePlan) (string, error) {
 	return protobuf.StreamID(command, command.PlanId), nil
 }
@@ -82,6 +125,8 @@ func eventTypeProvider(event *planproto.Event) (*onepiecemessage.MessageType, er
 	switch e := event.Event.(type) {
 	case *planproto.Event_PlanCreated:
 		return protobuf.MessageFullName(e.PlanCreated).AsMessageType()
+	case *planproto.Event_PlanUpdated:
+		return protobuf.MessageFullName(e.PlanUpdated).AsMessageType()
 	case *planproto.Event_PlanArchived:
 		return protobuf.MessageFullName(e.PlanArchived).AsMessageType()
 	case *planproto.Event_PlanDrained:
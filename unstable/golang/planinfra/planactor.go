@@ -3,17 +3,29 @@ package planinfra
 import (
 	"github.com/straw-hat-team/onepiece/go/onepiece"
 	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/protobuf"
 	"unstable/plandomain/planactor"
 	"unstable/plandomain/planproto"
 )
 
-var DispatchCommand = eventsourcing.NewDecider(
-	planactor.Decider,
-	streamID,
-	marshalEvent,
-	unmarshalEvent,
-	eventTypeProvider,
-)
+var DispatchCommand = NewDispatchCommand()
+
+// NewDispatchCommand returns DispatchCommand with options, such as the replay hook of onepiecemetrics.
+func NewDispatchCommand(options ...eventsourcing.DeciderOption) eventsourcing.CommandHandler[*planproto.Command, *planproto.Event] {
+	return eventsourcing.NewDecider(
+		planactor.Decider,
+		streamID,
+		marshalEvent,
+		unmarshalEvent,
+		eventTypeProvider,
+		options...,
+	)
+}
+
+// Validate checks that every command and event of the plan aggregate is wired into DispatchCommand.
+func Validate() error {
+	return protobuf.Validate(streamID, marshalEvent, unmarshalEvent, eventTypeProvider)
+}
 
 func streamID(command *planproto.Command) (string, error) {
 	switch c := command.Command.(type) {
@@ -21,6 +33,8 @@ func streamID(command *planproto.Command) (string, error) {
 		return createPlanStreamID(c.CreatePlan)
 	case *planproto.Command_ArchivePlan:
 		return archivePlanStreamID(c.ArchivePlan)
+	case *planproto.Command_UpdatePlan:
+		return updatePlanStreamID(c.UpdatePlan)
 	case *planproto.Command_DrainPlan:
 		return drainPlanStreamID(c.DrainPlan)
 	case *planproto.Command_FailDrainPlan:
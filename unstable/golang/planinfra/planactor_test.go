@@ -0,0 +1,50 @@
+package planinfra
+
+import (
+	"github.com/straw-hat-team/onepiece/go/onepiece"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecemessage"
+	"github.com/straw-hat-team/onepiece/go/onepiece/protobuf"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"unstable/plandomain/planproto"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("every command and event is wired", func(t *testing.T) {
+		require.NoError(t, Validate())
+	})
+
+	t.Run("reports every missing event type", func(t *testing.T) {
+		err := protobuf.Validate(
+			streamID,
+			marshalEvent,
+			unmarshalEvent,
+			func(event *planproto.Event) (*onepiecemessage.MessageType, error) {
+				if _, ok := event.Event.(*planproto.Event_PlanCreated); ok {
+					return eventTypeProvider(event)
+				}
+				return nil, onepiece.ErrUnknownEvent
+			},
+		)
+
+		require.ErrorIs(t, err, protobuf.ErrInvalidWiring)
+		require.ErrorIs(t, err, onepiece.ErrUnknownEvent)
+		require.ErrorContains(t, err, "com.hmbradley.deposit.plan.PlanUpdated")
+		require.ErrorContains(t, err, "com.hmbradley.deposit.plan.PlanDrainFailed")
+		require.NotContains(t, err.Error(), "com.hmbradley.deposit.plan.PlanCreated")
+	})
+
+	t.Run("reports commands without a stream id", func(t *testing.T) {
+		err := protobuf.Validate(
+			func(command *planproto.Command) (string, error) {
+				return "", onepiece.ErrUnknownCommand
+			},
+			marshalEvent,
+			unmarshalEvent,
+			eventTypeProvider,
+		)
+
+		require.ErrorIs(t, err, onepiece.ErrUnknownCommand)
+		require.ErrorContains(t, err, "com.hmbradley.deposit.plan.FailDrainPlan")
+	})
+}
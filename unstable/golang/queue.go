@@ -0,0 +1,293 @@
+package golang
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/protobuf"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// HeaderCommandId identifies a queued command. It keys the subject of its result and deduplicates the command within
+// the duplicate window of the stream.
+const HeaderCommandId = "Onepiece-Command-Id"
+
+var (
+	ErrQueueNameRequired = errors.New("queue name is required")
+	ErrCommandIdRequired = errors.New("command id is required")
+)
+
+var defaultQueueBackoff = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second}
+
+type CommandQueueConfig struct {
+	// Name of the JetStream stream holding the commands.
+	Name string
+	// Subject is the subject prefix of the queued commands, followed by the name of their message. Defaults to
+	// queue.onepiece.<Name>.
+	Subject string
+	// ResultSubject is the subject prefix of the results, followed by the command id. Defaults to
+	// results.onepiece.<Name>.
+	ResultSubject string
+	// Durable is the name of the consumer shared by the workers. Defaults to worker.
+	Durable string
+	// MaxDeliver is the number of attempts before a command failing with a transient error is given up. Defaults to 5.
+	MaxDeliver int
+	// Backoff is the delay before every retry of a transient error, the last one is used for any further retry.
+	// Defaults to 1s, 5s and 30s.
+	Backoff []time.Duration
+	// Logger reports every handled command. Defaults to slog.Default.
+	Logger *slog.Logger
+}
+
+// CommandQueue queues the variants of a Command oneof in a JetStream work queue, so they are handled asynchronously
+// by workers. The result of every command, either a CommandHandlerResponse or an error, is published to the result
+// subject of its command id and decoded with DecodeResponse.
+type CommandQueue[Command proto.Message] struct {
+	nc       *nats.Conn
+	js       jetstream.JetStream
+	config   CommandQueueConfig
+	registry *protobuf.OneofRegistry[Command]
+}
+
+// NewCommandQueue creates or updates the stream of the queue.
+func NewCommandQueue[Command proto.Message](
+	ctx context.Context,
+	nc *nats.Conn,
+	config CommandQueueConfig,
+) (*CommandQueue[Command], error) {
+	if config.Name == "" {
+		return nil, ErrQueueNameRequired
+	}
+	if config.Subject == "" {
+		config.Subject = fmt.Sprintf("queue.onepiece.%s", config.Name)
+	}
+	if config.ResultSubject == "" {
+		config.ResultSubject = fmt.Sprintf("results.onepiece.%s", config.Name)
+	}
+	if config.Durable == "" {
+		config.Durable = "worker"
+	}
+	if config.MaxDeliver <= 0 {
+		config.MaxDeliver = 5
+	}
+	if len(config.Backoff) == 0 {
+		config.Backoff = defaultQueueBackoff
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	registry, err := protobuf.NewOneofRegistry[Command]()
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      config.Name,
+		Subjects:  []string{config.Subject + ".>"},
+		Retention: jetstream.WorkQueuePolicy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommandQueue[Command]{
+		nc:       nc,
+		js:       js,
+		config:   config,
+		registry: registry,
+	}, nil
+}
+
+// ResultSubject returns the subject the result of the command is published to. Subscribe to it before enqueuing
+// the command to not miss the result.
+func (q *CommandQueue[Command]) ResultSubject(commandId string) string {
+	return fmt.Sprintf("%s.%s", q.config.ResultSubject, commandId)
+}
+
+// Enqueue publishes the variant set in the command to the queue, with the options as headers. Enqueuing the same
+// command id twice within the duplicate window of the stream queues the command once.
+func (q *CommandQueue[Command]) Enqueue(ctx context.Context, commandId string, command Command, opts *eventsourcing.Options) error {
+	if commandId == "" {
+		return ErrCommandIdRequired
+	}
+
+	variant, err := protobuf.OneofValue(command)
+	if err != nil {
+		return err
+	}
+	data, err := protojson.Marshal(variant)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(fmt.Sprintf("%s.%s", q.config.Subject, variant.ProtoReflect().Descriptor().Name()))
+	msg.Data = data
+	msg.Header.Set(HeaderCommandId, commandId)
+	if err := SetOptionsHeaders(msg.Header, opts); err != nil {
+		return err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier(msg.Header))
+
+	_, err = q.js.PublishMsg(ctx, msg, jetstream.WithMsgID(commandId))
+	return err
+}
+
+// Consume hands the queued commands to the handler, such as one returned by NewServiceCommandHandler, until the
+// returned ConsumeContext is stopped. Successful commands are acknowledged. Optimistic concurrency and
+// infrastructure errors are retried after the configured backoff until MaxDeliver is reached, while invalid commands
+// and domain errors are terminated right away. Either way, the result is published to the result subject.
+func (q *CommandQueue[Command]) Consume(ctx context.Context, handler ServiceCommandHandler[Command]) (jetstream.ConsumeContext, error) {
+	consumer, err := q.js.CreateOrUpdateConsumer(ctx, q.config.Name, jetstream.ConsumerConfig{
+		Durable:       q.config.Durable,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		MaxDeliver:    q.config.MaxDeliver,
+		FilterSubject: q.config.Subject + ".>",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger := q.config.Logger.With(slog.String("queue", q.config.Name))
+	return consumer.Consume(func(msg jetstream.Msg) {
+		q.handle(logger, msg, handler)
+	})
+}
+
+func (q *CommandQueue[Command]) handle(logger *slog.Logger, msg jetstream.Msg, handler ServiceCommandHandler[Command]) {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), HeaderCarrier(msg.Headers()))
+	ctx, span := tracer.Start(ctx, msg.Subject(), trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
+
+	commandId := msg.Headers().Get(HeaderCommandId)
+	logger = logger.With(slog.String("command_id", commandId))
+
+	command, opts, err := q.decode(msg)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		logger.WarnContext(ctx, "invalid command", slog.Any("error", err))
+		q.terminate(ctx, logger, msg, commandId, eventsourcing.ErrInvalidCommand.WithDetails(map[string]string{"reason": err.Error()}))
+		return
+	}
+
+	logger = logger.With(slog.String(eventsourcing.LogKeyCommandType, eventsourcing.MessageTypeOf(command)))
+
+	resp, err := handler(ctx, command, opts)
+	if err == nil {
+		logger.DebugContext(ctx, "command handled", slog.Uint64(eventsourcing.LogKeyRevision, resp.NextExpectedVersion))
+		if err := msg.Ack(); err != nil {
+			logger.ErrorContext(ctx, "failed to ack command", slog.Any("error", err))
+		}
+		q.publishResult(ctx, logger, commandId, resp, nil)
+		return
+	}
+
+	span.SetStatus(codes.Error, err.Error())
+	kind := eventsourcing.ErrorKindOf(err)
+	logger = logger.With(slog.String(eventsourcing.LogKeyErrorKind, string(kind)), slog.Any("error", err))
+
+	if kind == eventsourcing.ErrorKindConcurrency || kind == eventsourcing.ErrorKindInfrastructure {
+		delivered := uint64(1)
+		if metadata, err := msg.Metadata(); err == nil {
+			delivered = metadata.NumDelivered
+		}
+		if delivered < uint64(q.config.MaxDeliver) {
+			delay := q.backoff(delivered)
+			logger.WarnContext(ctx, "retrying command", slog.Uint64("delivered", delivered), slog.Duration("delay", delay))
+			if err := msg.NakWithDelay(delay); err != nil {
+				logger.ErrorContext(ctx, "failed to nak command", slog.Any("error", err))
+			}
+			return
+		}
+
+		logger.ErrorContext(ctx, "giving up command", slog.Uint64("delivered", delivered))
+		q.terminate(ctx, logger, msg, commandId, err)
+		return
+	}
+
+	logger.InfoContext(ctx, "command rejected")
+	q.terminate(ctx, logger, msg, commandId, err)
+}
+
+func (q *CommandQueue[Command]) decode(msg jetstream.Msg) (Command, *eventsourcing.Options, error) {
+	var command Command
+
+	name, ok := strings.CutPrefix(msg.Subject(), q.config.Subject+".")
+	if !ok {
+		return command, nil, fmt.Errorf("unexpected subject %s", msg.Subject())
+	}
+	command, err := q.registry.DecodeJSON(name, msg.Data())
+	if err != nil {
+		return command, nil, err
+	}
+	opts, err := OptionsFromHeaders(msg.Headers())
+	if err != nil {
+		return command, nil, err
+	}
+	return command, opts, nil
+}
+
+func (q *CommandQueue[Command]) backoff(delivered uint64) time.Duration {
+	i := int(delivered) - 1
+	if i >= len(q.config.Backoff) {
+		i = len(q.config.Backoff) - 1
+	}
+	return q.config.Backoff[i]
+}
+
+func (q *CommandQueue[Command]) terminate(ctx context.Context, logger *slog.Logger, msg jetstream.Msg, commandId string, err error) {
+	if err := msg.Term(); err != nil {
+		logger.ErrorContext(ctx, "failed to terminate command", slog.Any("error", err))
+	}
+	q.publishResult(ctx, logger, commandId, nil, err)
+}
+
+func (q *CommandQueue[Command]) publishResult(
+	ctx context.Context,
+	logger *slog.Logger,
+	commandId string,
+	resp *CommandHandlerResponse,
+	handlerErr error,
+) {
+	if commandId == "" {
+		return
+	}
+
+	subject := q.ResultSubject(commandId)
+	msg := nats.NewMsg(subject)
+	if handlerErr != nil {
+		errMsg, err := newErrorMsg(subject, handlerErr)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to encode result", slog.Any("error", err))
+			return
+		}
+		msg = errMsg
+	} else {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to encode result", slog.Any("error", err))
+			return
+		}
+		msg.Data = data
+	}
+
+	if err := q.nc.PublishMsg(msg); err != nil {
+		logger.ErrorContext(ctx, "failed to publish result", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,128 @@
+package golang_test
+
+import (
+	"context"
+	"errors"
+	"github.com/nats-io/nats.go"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+	golang "unstable"
+	"unstable/plandomain/planactor"
+	"unstable/plandomain/planproto"
+)
+
+type flakyHandler struct {
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+// handle fails the plans named after an error the first two times they are handled.
+func (h *flakyHandler) handle(_ context.Context, command *planproto.Command, _ *eventsourcing.Options) (*golang.CommandHandlerResponse, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	planId := command.GetArchivePlan().GetPlanId()
+	h.attempts[planId]++
+	attempts := h.attempts[planId]
+
+	switch planId {
+	case "not-found":
+		return nil, planactor.ErrPlanNotFound
+	case "conflict":
+		if attempts <= 2 {
+			return nil, eventsourcing.ErrOptimisticConcurrency
+		}
+	case "down":
+		return nil, errors.New("connection refused")
+	}
+	return &golang.CommandHandlerResponse{NextExpectedVersion: uint64(attempts)}, nil
+}
+
+func (h *flakyHandler) attemptsOf(planId string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.attempts[planId]
+}
+
+func TestCommandQueue(t *testing.T) {
+	nc := runNats(t)
+	ctx := context.Background()
+
+	queue, err := golang.NewCommandQueue[*planproto.Command](ctx, nc, golang.CommandQueueConfig{
+		Name:       "plans",
+		MaxDeliver: 3,
+		Backoff:    []time.Duration{10 * time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	handler := &flakyHandler{attempts: map[string]int{}}
+	consumer, err := queue.Consume(ctx, handler.handle)
+	require.NoError(t, err)
+	t.Cleanup(consumer.Stop)
+
+	enqueue := func(t *testing.T, commandId string, planId string) (*golang.CommandHandlerResponse, error) {
+		t.Helper()
+
+		sub, err := nc.SubscribeSync(queue.ResultSubject(commandId))
+		require.NoError(t, err)
+		defer sub.Unsubscribe()
+
+		command := &planproto.Command{Command: &planproto.Command_ArchivePlan{ArchivePlan: &planproto.ArchivePlan{PlanId: planId}}}
+		require.NoError(t, queue.Enqueue(ctx, commandId, command, nil))
+
+		msg, err := sub.NextMsg(5 * time.Second)
+		require.NoError(t, err)
+		return golang.DecodeResponse(msg)
+	}
+
+	t.Run("publishes the result of handled commands", func(t *testing.T) {
+		resp, err := enqueue(t, "command-1", "ok")
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), resp.NextExpectedVersion)
+	})
+
+	t.Run("terminates domain errors right away", func(t *testing.T) {
+		_, err := enqueue(t, "command-2", "not-found")
+		require.ErrorIs(t, err, planactor.ErrPlanNotFound)
+		require.Equal(t, 1, handler.attemptsOf("not-found"))
+	})
+
+	t.Run("retries optimistic concurrency errors", func(t *testing.T) {
+		resp, err := enqueue(t, "command-3", "conflict")
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), resp.NextExpectedVersion)
+	})
+
+	t.Run("gives up transient errors after max deliver", func(t *testing.T) {
+		_, err := enqueue(t, "command-4", "down")
+
+		var serviceErr *golang.ServiceError
+		require.ErrorAs(t, err, &serviceErr)
+		require.Equal(t, "500", serviceErr.Code)
+		require.Equal(t, 3, handler.attemptsOf("down"))
+	})
+
+	t.Run("terminates invalid commands", func(t *testing.T) {
+		sub, err := nc.SubscribeSync(queue.ResultSubject("command-5"))
+		require.NoError(t, err)
+		defer sub.Unsubscribe()
+
+		msg := nats.NewMsg("queue.onepiece.plans.ArchivePlan")
+		msg.Header.Set(golang.HeaderCommandId, "command-5")
+		msg.Data = []byte(`{"planId": 1}`)
+		require.NoError(t, nc.PublishMsg(msg))
+
+		result, err := sub.NextMsg(5 * time.Second)
+		require.NoError(t, err)
+		_, err = golang.DecodeResponse(result)
+		require.ErrorIs(t, err, eventsourcing.ErrInvalidCommand)
+	})
+
+	t.Run("requires a command id", func(t *testing.T) {
+		err := queue.Enqueue(ctx, "", &planproto.Command{}, nil)
+		require.ErrorIs(t, err, golang.ErrCommandIdRequired)
+	})
+}
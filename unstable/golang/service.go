@@ -0,0 +1,142 @@
+package golang
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	services "github.com/nats-io/nats.go/micro"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/protobuf"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"log/slog"
+)
+
+// HeaderCommandType names the Command variant carried by a request sent to the dispatch endpoint, by the short or
+// full name of its message.
+const HeaderCommandType = "Onepiece-Command-Type"
+
+const (
+	dispatchEndpointName = "dispatch"
+	defaultVersion       = "1.0.0"
+)
+
+var ErrServiceNameRequired = errors.New("service name is required")
+
+type ServiceConfig struct {
+	// Name of the service, used for discovery.
+	Name string
+	// Version of the service, must be a valid semantic version. Defaults to 1.0.0.
+	Version     string
+	Description string
+	Metadata    map[string]string
+	// Group is the subject prefix shared by every endpoint. Defaults to svc.onepiece.<Name>.
+	Group string
+	// Logger reports the service lifecycle and every handled request. Defaults to slog.Default.
+	Logger *slog.Logger
+}
+
+// NewAggregateService serves every variant of a Command oneof in one NATS micro service. Each variant gets its own
+// endpoint, and thereby its own stats, named after its message and receiving the protojson of that message, e.g.
+// svc.onepiece.plan.CreatePlan. A dispatch endpoint additionally accepts either a variant named by the
+// Onepiece-Command-Type header, or the protojson of the whole Command when the header is missing.
+func NewAggregateService[Command proto.Message](
+	nc *nats.Conn,
+	config ServiceConfig,
+	appHandler ServiceCommandHandler[Command],
+) (services.Service, error) {
+	if config.Name == "" {
+		return nil, ErrServiceNameRequired
+	}
+	if config.Version == "" {
+		config.Version = defaultVersion
+	}
+	if config.Group == "" {
+		config.Group = fmt.Sprintf("svc.onepiece.%s", config.Name)
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	registry, err := protobuf.NewOneofRegistry[Command]()
+	if err != nil {
+		return nil, err
+	}
+
+	logger := config.Logger.With(slog.String("service", config.Name), slog.String("group", config.Group))
+
+	svc, err := services.AddService(nc, services.Config{
+		Name:        config.Name,
+		Version:     config.Version,
+		Description: config.Description,
+		Metadata:    config.Metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	group := svc.AddGroup(config.Group)
+
+	for _, name := range registry.Names() {
+		commandName := name.Name()
+		subject := fmt.Sprintf("%s.%s", config.Group, commandName)
+		handler := services.HandlerFunc(func(req services.Request) {
+			serveCommand(logger, subject, req, func(req services.Request) (Command, error) {
+				return registry.DecodeJSON(commandName, req.Data())
+			}, appHandler)
+		})
+
+		err := group.AddEndpoint(commandName, handler, services.WithEndpointMetadata(map[string]string{
+			"command_type": name.String(),
+		}))
+		if err != nil {
+			return nil, errors.Join(err, svc.Stop())
+		}
+	}
+
+	dispatchSubject := fmt.Sprintf("%s.%s", config.Group, dispatchEndpointName)
+	dispatchHandler := services.HandlerFunc(func(req services.Request) {
+		serveCommand(logger, dispatchSubject, req, func(req services.Request) (Command, error) {
+			return decodeDispatch(registry, req)
+		}, appHandler)
+	})
+	if err := group.AddEndpoint(dispatchEndpointName, dispatchHandler); err != nil {
+		return nil, errors.Join(err, svc.Stop())
+	}
+
+	logger.Info("started service", slog.Int("commands", len(registry.Names())))
+
+	return svc, nil
+}
+
+func decodeDispatch[Command proto.Message](registry *protobuf.OneofRegistry[Command], req services.Request) (Command, error) {
+	if commandType := req.Headers().Get(HeaderCommandType); commandType != "" {
+		return registry.DecodeJSON(commandType, req.Data())
+	}
+
+	var command Command
+	command = command.ProtoReflect().Type().New().Interface().(Command)
+	if err := protojson.Unmarshal(req.Data(), command); err != nil {
+		return command, err
+	}
+	return command, nil
+}
+
+// NewServiceCommandHandler adapts a CommandHandler, such as the ones returned by eventsourcing.NewDecider, to be
+// served over NATS with the eventsourcing.Options sent in the request headers.
+func NewServiceCommandHandler[Command any, Event any](
+	store eventsourcing.EventStore,
+	handler eventsourcing.CommandHandler[Command, Event],
+) ServiceCommandHandler[Command] {
+	return func(ctx context.Context, command Command, opts *eventsourcing.Options) (*CommandHandlerResponse, error) {
+		result, err := handler(ctx, store, command, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		return &CommandHandlerResponse{
+			NextExpectedVersion: result.NextExpectedVersion,
+		}, nil
+	}
+}
@@ -0,0 +1,195 @@
+package golang_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	services "github.com/nats-io/nats.go/micro"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"sync"
+	"testing"
+	"time"
+	golang "unstable"
+	"unstable/plandomain/planproto"
+)
+
+func runNats(t *testing.T) *nats.Conn {
+	t.Helper()
+
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, JetStream: true, StoreDir: t.TempDir()})
+	require.NoError(t, err)
+	ns.Start()
+	t.Cleanup(ns.Shutdown)
+	require.True(t, ns.ReadyForConnections(5*time.Second), "nats server is not ready")
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+
+	return nc
+}
+
+type recordingHandler struct {
+	mu       sync.Mutex
+	commands []*planproto.Command
+	opts     []*eventsourcing.Options
+}
+
+func (h *recordingHandler) handle(_ context.Context, command *planproto.Command, opts *eventsourcing.Options) (*golang.CommandHandlerResponse, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commands = append(h.commands, command)
+	h.opts = append(h.opts, opts)
+	return &golang.CommandHandlerResponse{NextExpectedVersion: uint64(len(h.commands))}, nil
+}
+
+func (h *recordingHandler) last() *planproto.Command {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.commands[len(h.commands)-1]
+}
+
+func (h *recordingHandler) lastOptions() *eventsourcing.Options {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.opts[len(h.opts)-1]
+}
+
+func requireResponse(t *testing.T, msg *nats.Msg, want uint64) {
+	t.Helper()
+	require.Empty(t, msg.Header.Get(services.ErrorHeader), "unexpected error: %s", msg.Data)
+
+	var resp golang.CommandHandlerResponse
+	require.NoError(t, json.Unmarshal(msg.Data, &resp))
+	require.Equal(t, want, resp.NextExpectedVersion)
+}
+
+func TestNewAggregateService(t *testing.T) {
+	nc := runNats(t)
+	handler := &recordingHandler{}
+
+	svc, err := golang.NewAggregateService[*planproto.Command](nc, golang.ServiceConfig{
+		Name:        "plan",
+		Version:     "2.1.0",
+		Description: "plan commands",
+		Metadata:    map[string]string{"team": "deposits"},
+	}, handler.handle)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = svc.Stop() })
+
+	createPlan := &planproto.Command{Command: &planproto.Command_CreatePlan{CreatePlan: &planproto.CreatePlan{
+		PlanId: "d83a3744-0e53-4fb7-88f7-7ffc831f0090",
+		Title:  "Vacation",
+	}}}
+
+	t.Run("exposes one endpoint per command", func(t *testing.T) {
+		info := svc.Info()
+		require.Equal(t, "2.1.0", info.Version)
+		require.Equal(t, "plan commands", info.Description)
+		require.Equal(t, map[string]string{"team": "deposits"}, info.Metadata)
+
+		subjects := map[string]string{}
+		for _, endpoint := range info.Endpoints {
+			subjects[endpoint.Name] = endpoint.Subject
+		}
+		require.Equal(t, map[string]string{
+			"CreatePlan":    "svc.onepiece.plan.CreatePlan",
+			"ArchivePlan":   "svc.onepiece.plan.ArchivePlan",
+			"UpdatePlan":    "svc.onepiece.plan.UpdatePlan",
+			"DrainPlan":     "svc.onepiece.plan.DrainPlan",
+			"FailDrainPlan": "svc.onepiece.plan.FailDrainPlan",
+			"dispatch":      "svc.onepiece.plan.dispatch",
+		}, subjects)
+	})
+
+	t.Run("routes a command endpoint to the handler", func(t *testing.T) {
+		data, err := protojson.Marshal(createPlan.GetCreatePlan())
+		require.NoError(t, err)
+
+		msg, err := nc.Request("svc.onepiece.plan.CreatePlan", data, time.Second)
+		require.NoError(t, err)
+
+		requireResponse(t, msg, 1)
+		require.True(t, proto.Equal(createPlan, handler.last()))
+	})
+
+	t.Run("routes the dispatch endpoint by command type header", func(t *testing.T) {
+		data, err := protojson.Marshal(createPlan.GetCreatePlan())
+		require.NoError(t, err)
+
+		msg := nats.NewMsg("svc.onepiece.plan.dispatch")
+		msg.Header.Set(golang.HeaderCommandType, "com.hmbradley.deposit.plan.CreatePlan")
+		msg.Data = data
+		resp, err := nc.RequestMsg(msg, time.Second)
+		require.NoError(t, err)
+
+		requireResponse(t, resp, 2)
+		require.True(t, proto.Equal(createPlan, handler.last()))
+	})
+
+	t.Run("routes the dispatch endpoint by command wrapper", func(t *testing.T) {
+		data, err := protojson.Marshal(createPlan)
+		require.NoError(t, err)
+
+		msg, err := nc.Request("svc.onepiece.plan.dispatch", data, time.Second)
+		require.NoError(t, err)
+
+		requireResponse(t, msg, 3)
+		require.True(t, proto.Equal(createPlan, handler.last()))
+	})
+
+	t.Run("rejects unknown command types", func(t *testing.T) {
+		msg := nats.NewMsg("svc.onepiece.plan.dispatch")
+		msg.Header.Set(golang.HeaderCommandType, "DeletePlan")
+		msg.Data = []byte(`{}`)
+		resp, err := nc.RequestMsg(msg, time.Second)
+		require.NoError(t, err)
+
+		require.NotEmpty(t, resp.Header.Get(services.ErrorHeader))
+	})
+
+	t.Run("keeps stats per endpoint", func(t *testing.T) {
+		requests := map[string]int{}
+		for _, endpoint := range svc.Stats().Endpoints {
+			requests[endpoint.Name] = endpoint.NumRequests
+		}
+		require.Equal(t, 1, requests["CreatePlan"])
+		require.Equal(t, 0, requests["ArchivePlan"])
+		require.Equal(t, 3, requests["dispatch"])
+	})
+
+	t.Run("maps request headers into options", func(t *testing.T) {
+		data, err := protojson.Marshal(createPlan.GetCreatePlan())
+		require.NoError(t, err)
+
+		msg := nats.NewMsg("svc.onepiece.plan.CreatePlan")
+		msg.Header.Set(golang.HeaderExpectedRevision, "3")
+		msg.Header.Set(golang.HeaderCorrelationId, "correlation")
+		msg.Header.Set(golang.HeaderMetadataPrefix+"User-Id", "user")
+		msg.Data = data
+		resp, err := nc.RequestMsg(msg, time.Second)
+		require.NoError(t, err)
+
+		requireResponse(t, resp, 4)
+		correlationId := eventsourcing.CorrelationId("correlation")
+		require.Equal(t, &eventsourcing.Options{
+			ExpectedRevision: eventsourcing.Revision(3),
+			CorrelationId:    &correlationId,
+			Metadata:         eventsourcing.Metadata{"User-Id": "user"},
+		}, handler.lastOptions())
+	})
+
+	t.Run("rejects invalid expected revisions", func(t *testing.T) {
+		msg := nats.NewMsg("svc.onepiece.plan.CreatePlan")
+		msg.Header.Set(golang.HeaderExpectedRevision, "latest")
+		msg.Data = []byte(`{}`)
+		resp, err := nc.RequestMsg(msg, time.Second)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, golang.DecodeError(resp), eventsourcing.ErrInvalidCommand)
+	})
+}
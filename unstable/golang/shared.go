@@ -1,11 +1,18 @@
 package golang
 
 import (
+	"context"
 	"fmt"
 	"github.com/EventStore/EventStore-Client-Go/v3/esdb"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 	services "github.com/nats-io/nats.go/micro"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
 	"os"
 	"strings"
 )
@@ -34,6 +41,34 @@ func NewNats() (*nats.Conn, jetstream.JetStream) {
 	return nc, js
 }
 
+var tracer = otel.Tracer("unstable")
+
+// SetupTracePropagation makes the global OpenTelemetry propagator carry W3C trace context and baggage, so traces
+// follow commands through NATS headers and event metadata.
+func SetupTracePropagation() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// HeaderCarrier adapts NATS headers to a propagation.TextMapCarrier. Unlike propagation.HeaderCarrier it does not
+// canonicalize keys, since NATS headers are case-sensitive.
+type HeaderCarrier nats.Header
+
+func (c HeaderCarrier) Get(key string) string {
+	return nats.Header(c).Get(key)
+}
+
+func (c HeaderCarrier) Set(key string, value string) {
+	nats.Header(c).Set(key, value)
+}
+
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
 func Must(err error) {
 	if err != nil {
 		panic(err)
@@ -43,45 +78,112 @@ func Must(err error) {
 type CommandHandlerResponse struct {
 	NextExpectedVersion uint64 `json:"nextExpectedVersion"`
 }
-type ServiceCommandHandler[Command any] func(command Command) (
+
+// ServiceCommandHandler handles a command received over NATS, together with the eventsourcing.Options read from the
+// request headers.
+type ServiceCommandHandler[Command any] func(ctx context.Context, command Command, opts *eventsourcing.Options) (
 	*CommandHandlerResponse,
 	error,
 )
 
 type UnmarshalCommand[Command any] func(data []byte) (Command, error)
 
+type ServiceOption func(config *serviceConfig)
+
+type serviceConfig struct {
+	logger *slog.Logger
+}
+
+// WithLogger sets the logger used to report the service lifecycle and every handled request. Defaults to
+// slog.Default.
+func WithLogger(logger *slog.Logger) ServiceOption {
+	return func(config *serviceConfig) {
+		config.logger = logger
+	}
+}
+
 func NewService[Command any](
 	nc *nats.Conn,
 	serviceName string,
 	subjectName string,
 	unmarshalCommand UnmarshalCommand[Command],
 	appHandler ServiceCommandHandler[Command],
+	options ...ServiceOption,
 ) (services.Service, error) {
+	config := &serviceConfig{logger: slog.Default()}
+	for _, option := range options {
+		option(config)
+	}
+
 	fullSubjectName := fmt.Sprintf("svc.onepiece.%s", subjectName)
 	fullServiceName := fmt.Sprintf("onepiece-%s", serviceName)
+	logger := config.logger.With(slog.String("service", fullServiceName), slog.String("subject", fullSubjectName))
 
-	fmt.Printf("service name: %s\n", fullServiceName)
-	fmt.Printf("service subject: %s\n", fullSubjectName)
+	logger.Info("starting service")
 	return services.AddService(nc, services.Config{
 		Name:    fullServiceName,
 		Version: "1.0.0",
 		Endpoint: &services.EndpointConfig{
 			Subject: fullSubjectName,
 			Handler: services.HandlerFunc(func(req services.Request) {
-				command, err := unmarshalCommand(req.Data())
-				if err != nil {
-					req.Error("error", err.Error(), nil)
-					return
-				}
-
-				resp, err := appHandler(command)
-				if err != nil {
-					req.Error("error", err.Error(), nil)
-					return
-				}
-
-				req.RespondJSON(resp)
+				serveCommand(logger, fullSubjectName, req, func(req services.Request) (Command, error) {
+					return unmarshalCommand(req.Data())
+				}, appHandler)
 			}),
 		},
 	})
 }
+
+// serveCommand decodes the command of a request, hands it to the application handler and responds with either the
+// CommandHandlerResponse or an error, tracing and logging every step.
+func serveCommand[Command any](
+	logger *slog.Logger,
+	spanName string,
+	req services.Request,
+	decode func(req services.Request) (Command, error),
+	appHandler ServiceCommandHandler[Command],
+) {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), HeaderCarrier(req.Headers()))
+	ctx, span := tracer.Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
+	command, err := decode(req)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		logger.WarnContext(ctx, "invalid command", slog.Any("error", err))
+		_ = respondError(req, eventsourcing.ErrInvalidCommand.WithDetails(map[string]string{"reason": err.Error()}))
+		return
+	}
+
+	opts, err := OptionsFromHeaders(nats.Header(req.Headers()))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		logger.WarnContext(ctx, "invalid command options", slog.Any("error", err))
+		_ = respondError(req, eventsourcing.ErrInvalidCommand.WithDetails(map[string]string{"reason": err.Error()}))
+		return
+	}
+
+	requestLogger := logger.With(slog.String(eventsourcing.LogKeyCommandType, eventsourcing.MessageTypeOf(command)))
+
+	resp, err := appHandler(ctx, command, opts)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		kind := eventsourcing.ErrorKindOf(err)
+		level, msg := slog.LevelInfo, "command rejected"
+		if kind == eventsourcing.ErrorKindInfrastructure {
+			// NOTE: the reply only says internal error, so the cause is only found here.
+			level, msg = slog.LevelError, "command failed"
+		}
+		requestLogger.Log(ctx, level, msg,
+			slog.String(eventsourcing.LogKeyErrorKind, string(kind)),
+			slog.Any("error", err),
+		)
+		if err := respondError(req, err); err != nil {
+			requestLogger.ErrorContext(ctx, "failed to respond", slog.Any("error", err))
+		}
+		return
+	}
+
+	requestLogger.DebugContext(ctx, "command handled", slog.Uint64(eventsourcing.LogKeyRevision, resp.NextExpectedVersion))
+	req.RespondJSON(resp)
+}
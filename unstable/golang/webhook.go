@@ -0,0 +1,536 @@
+package golang
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecemessage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers of the requests sent by a WebhookDispatcher. The event id is sent in HeaderEventId.
+const (
+	HeaderWebhookDeliveryId = "Onepiece-Webhook-Delivery-Id"
+	HeaderWebhookTimestamp  = "Onepiece-Webhook-Timestamp"
+	HeaderWebhookSignature  = "Onepiece-Webhook-Signature"
+)
+
+const (
+	defaultWebhookMaxAttempts = 5
+	defaultWebhookMinBackoff  = time.Second
+	defaultWebhookMaxBackoff  = time.Minute
+	defaultWebhookTimeout     = 10 * time.Second
+	webhookSignaturePrefix    = "sha256="
+)
+
+var (
+	ErrWebhookSubscriptionInvalid = errors.New("invalid webhook subscription")
+	ErrWebhookDeliveryNotParked   = errors.New("webhook delivery is not parked")
+	ErrWebhookDeliveryFailed      = errors.New("webhook delivery failed")
+	ErrWebhookSignatureInvalid    = errors.New("invalid webhook signature")
+)
+
+// WebhookEnvelope is the JSON body of a webhook request. Data holds the event as JSON, or as a base64 string when the
+// event is binary.
+type WebhookEnvelope struct {
+	Id            string          `json:"id"`
+	Type          string          `json:"type"`
+	StreamId      string          `json:"streamId"`
+	Revision      uint64          `json:"revision"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	CorrelationId string          `json:"correlationId,omitempty"`
+	CausationId   string          `json:"causationId,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// WebhookSubscription is an external consumer of the events.
+type WebhookSubscription struct {
+	// Name identifies the subscription in the DeliveryLog.
+	Name string
+	// URL receives a POST request per event.
+	URL string
+	// Secret signs the requests, see VerifyWebhookSignature.
+	Secret []byte
+	// MessageTypes are the types of the events delivered to the subscription. Every event is delivered when empty.
+	MessageTypes []onepiecemessage.MessageType
+}
+
+func (s *WebhookSubscription) accepts(event *RecordedEvent) bool {
+	if len(s.MessageTypes) == 0 {
+		return true
+	}
+	return slices.Contains(s.MessageTypes, onepiecemessage.MessageType(event.Type))
+}
+
+// DeliveryAttempt is a single request sent to a WebhookSubscription.
+type DeliveryAttempt struct {
+	DeliveryId   string
+	Subscription string
+	EventId      string
+	Attempt      int
+	// StatusCode is the status of the response, zero when no response was received.
+	StatusCode int
+	Error      string
+	At         time.Time
+	Duration   time.Duration
+}
+
+// ParkedDelivery is a delivery that failed every attempt, kept until it is replayed.
+type ParkedDelivery struct {
+	DeliveryId   string
+	Subscription string
+	Event        RecordedEvent
+	Attempts     int
+	LastError    string
+	ParkedAt     time.Time
+}
+
+// DeliveryLog persists the state of a WebhookDispatcher.
+type DeliveryLog interface {
+	RecordAttempt(ctx context.Context, attempt DeliveryAttempt) error
+	Park(ctx context.Context, delivery ParkedDelivery) error
+	// Unpark removes a parked delivery, returning ErrWebhookDeliveryNotParked when it does not exist.
+	Unpark(ctx context.Context, deliveryId string) (*ParkedDelivery, error)
+	Parked(ctx context.Context) ([]ParkedDelivery, error)
+	// Checkpoint returns the position of the last event handled for the subscription, or nil when no event was
+	// handled yet.
+	Checkpoint(ctx context.Context, subscription string) (*EventPosition, error)
+	SaveCheckpoint(ctx context.Context, subscription string, position EventPosition) error
+}
+
+type WebhookConfig struct {
+	Subscriptions []WebhookSubscription
+	// Deliveries records the attempts, the parked deliveries and the checkpoint. Defaults to a MemoryDeliveryLog.
+	Deliveries DeliveryLog
+	// Client sends the requests. Defaults to a client with a 10s timeout.
+	Client *http.Client
+	// MaxAttempts is the number of attempts before a delivery is parked. Defaults to 5.
+	MaxAttempts int
+	// MinBackoff is the delay before the second attempt, doubled on every following attempt. Defaults to 1s.
+	MinBackoff time.Duration
+	// MaxBackoff caps the delay between attempts. Defaults to 1m.
+	MaxBackoff time.Duration
+	// Logger reports every delivery. Defaults to slog.Default.
+	Logger *slog.Logger
+}
+
+// WebhookDispatcher delivers the events of an EventSource to WebhookSubscriptions, at least once and in order. Every
+// subscription reads the source from its own checkpoint, so a failing subscription only holds back its own events.
+// Failed deliveries are retried with an exponential backoff, then parked so the following events are not held back.
+type WebhookDispatcher struct {
+	source        EventSource
+	config        WebhookConfig
+	subscriptions map[string]*WebhookSubscription
+	logger        *slog.Logger
+}
+
+func NewWebhookDispatcher(source EventSource, config WebhookConfig) (*WebhookDispatcher, error) {
+	subscriptions := make(map[string]*WebhookSubscription, len(config.Subscriptions))
+	for i := range config.Subscriptions {
+		subscription := &config.Subscriptions[i]
+		if subscription.Name == "" || subscription.URL == "" || len(subscription.Secret) == 0 {
+			return nil, fmt.Errorf("%w: name, url and secret are required: %q", ErrWebhookSubscriptionInvalid, subscription.Name)
+		}
+		if _, ok := subscriptions[subscription.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicated name: %q", ErrWebhookSubscriptionInvalid, subscription.Name)
+		}
+		subscriptions[subscription.Name] = subscription
+	}
+	if config.Deliveries == nil {
+		config.Deliveries = NewMemoryDeliveryLog()
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultWebhookMaxAttempts
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaultWebhookMinBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultWebhookMaxBackoff
+	}
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	return &WebhookDispatcher{
+		source:        source,
+		config:        config,
+		subscriptions: subscriptions,
+		logger:        config.Logger,
+	}, nil
+}
+
+// Run delivers the events after the checkpoint of every subscription until the context is done, which is not reported
+// as an error. A subscription failing with anything else than a parked delivery stops every subscription.
+func (d *WebhookDispatcher) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for i := range d.config.Subscriptions {
+		subscription := &d.config.Subscriptions[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.run(ctx, subscription); err != nil && ctx.Err() == nil {
+				cancel(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	d.logger.InfoContext(ctx, "stopped webhook dispatcher")
+	return nil
+}
+
+func (d *WebhookDispatcher) run(ctx context.Context, subscription *WebhookSubscription) error {
+	from, err := d.config.Deliveries.Checkpoint(ctx, subscription.Name)
+	if err != nil {
+		return err
+	}
+
+	return d.source.Subscribe(ctx, from, func(ctx context.Context, event *RecordedEvent) error {
+		return d.handle(ctx, subscription, event)
+	})
+}
+
+func (d *WebhookDispatcher) handle(ctx context.Context, subscription *WebhookSubscription, event *RecordedEvent) error {
+	if !strings.HasPrefix(event.StreamId, "$") && !strings.HasPrefix(event.Type, "$") && subscription.accepts(event) {
+		if err := d.deliver(ctx, subscription, event); err != nil && !errors.Is(err, ErrWebhookDeliveryFailed) {
+			return err
+		}
+	}
+	return d.config.Deliveries.SaveCheckpoint(ctx, subscription.Name, event.Position)
+}
+
+// Replay delivers a parked delivery again. It is parked back when every attempt fails.
+func (d *WebhookDispatcher) Replay(ctx context.Context, deliveryId string) error {
+	parked, err := d.config.Deliveries.Unpark(ctx, deliveryId)
+	if err != nil {
+		return err
+	}
+	subscription, ok := d.subscriptions[parked.Subscription]
+	if !ok {
+		_ = d.config.Deliveries.Park(ctx, *parked)
+		return fmt.Errorf("%w: unknown subscription: %q", ErrWebhookSubscriptionInvalid, parked.Subscription)
+	}
+	err = d.deliver(ctx, subscription, &parked.Event)
+	if err != nil && !errors.Is(err, ErrWebhookDeliveryFailed) {
+		return errors.Join(err, d.config.Deliveries.Park(ctx, *parked))
+	}
+	return err
+}
+
+// deliver sends the event until the subscription accepts it, parking the delivery after the last attempt. It returns
+// ErrWebhookDeliveryFailed when the delivery was parked.
+func (d *WebhookDispatcher) deliver(ctx context.Context, subscription *WebhookSubscription, event *RecordedEvent) error {
+	deliveryId := WebhookDeliveryId(subscription.Name, event.Id)
+	logger := d.logger.With(
+		slog.String("subscription", subscription.Name),
+		slog.String("delivery_id", deliveryId),
+		slog.String(eventsourcing.LogKeyStreamId, event.StreamId),
+		slog.String("event_type", event.Type),
+	)
+
+	body, metadata, err := newWebhookEnvelope(event)
+	if err != nil {
+		// NOTE: an event that cannot be encoded never will be, so it is parked right away instead of stopping the
+		// subscription.
+		return d.park(ctx, logger, subscription, deliveryId, event, 0, err)
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= d.config.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(d.backoff(attempt - 1)):
+			}
+		}
+
+		start := time.Now()
+		statusCode, err := d.send(ctx, subscription, deliveryId, event, metadata, body)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		record := DeliveryAttempt{
+			DeliveryId:   deliveryId,
+			Subscription: subscription.Name,
+			EventId:      event.Id,
+			Attempt:      attempt,
+			StatusCode:   statusCode,
+			At:           start,
+			Duration:     time.Since(start),
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+		if err := d.config.Deliveries.RecordAttempt(ctx, record); err != nil {
+			return err
+		}
+		if err == nil {
+			logger.DebugContext(ctx, "webhook delivered", slog.Int("attempt", attempt))
+			return nil
+		}
+
+		lastErr = err
+		logger.WarnContext(ctx, "webhook delivery attempt failed", slog.Int("attempt", attempt), slog.Any("error", err))
+	}
+
+	return d.park(ctx, logger, subscription, deliveryId, event, d.config.MaxAttempts, lastErr)
+}
+
+// park parks the delivery and returns ErrWebhookDeliveryFailed wrapping the cause, or the error of the DeliveryLog.
+func (d *WebhookDispatcher) park(
+	ctx context.Context,
+	logger *slog.Logger,
+	subscription *WebhookSubscription,
+	deliveryId string,
+	event *RecordedEvent,
+	attempts int,
+	cause error,
+) error {
+	logger.ErrorContext(ctx, "parking webhook delivery", slog.Any("error", cause))
+	err := d.config.Deliveries.Park(ctx, ParkedDelivery{
+		DeliveryId:   deliveryId,
+		Subscription: subscription.Name,
+		Event:        *event,
+		Attempts:     attempts,
+		LastError:    cause.Error(),
+		ParkedAt:     time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrWebhookDeliveryFailed, cause)
+}
+
+// send posts the envelope, returning the status code of the response and an error unless it is a 2xx.
+func (d *WebhookDispatcher) send(
+	ctx context.Context,
+	subscription *WebhookSubscription,
+	deliveryId string,
+	event *RecordedEvent,
+	metadata eventsourcing.Metadata,
+	body []byte,
+) (_ int, err error) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, eventsourcing.MetadataCarrier(metadata))
+	ctx, span := tracer.Start(ctx, "webhook "+subscription.Name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("onepiece.stream_id", event.StreamId),
+			attribute.String("onepiece.event_type", event.Type),
+		),
+	)
+	defer func() {
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventId, event.Id)
+	req.Header.Set(HeaderWebhookDeliveryId, deliveryId)
+	req.Header.Set(HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(subscription.Secret, timestamp, body))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := d.config.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *WebhookDispatcher) backoff(retries int) time.Duration {
+	backoff := d.config.MinBackoff
+	for i := 1; i < retries && backoff < d.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, d.config.MaxBackoff)
+}
+
+// WebhookDeliveryId identifies the delivery of an event to a subscription, receivers use it to deduplicate requests.
+func WebhookDeliveryId(subscription string, eventId string) string {
+	return subscription + ":" + eventId
+}
+
+// newWebhookEnvelope encodes the event sent to the subscriptions. Malformed metadata is an error, since the envelope
+// would miss its correlation ids and trace context.
+func newWebhookEnvelope(event *RecordedEvent) ([]byte, eventsourcing.Metadata, error) {
+	metadata := eventsourcing.Metadata{}
+	if len(event.Metadata) > 0 {
+		if err := json.Unmarshal(event.Metadata, &metadata); err != nil {
+			return nil, nil, fmt.Errorf("malformed event metadata: %w", err)
+		}
+	}
+	carrier := eventsourcing.MetadataCarrier(metadata)
+
+	data := json.RawMessage(event.Data)
+	if !isJSONContentType(event.ContentType) {
+		encoded, err := json.Marshal(event.Data)
+		if err != nil {
+			return nil, nil, err
+		}
+		data = encoded
+	}
+
+	body, err := json.Marshal(WebhookEnvelope{
+		Id:            event.Id,
+		Type:          event.Type,
+		StreamId:      event.StreamId,
+		Revision:      event.Revision,
+		CreatedAt:     event.CreatedAt,
+		CorrelationId: carrier.Get("$correlationId"),
+		CausationId:   carrier.Get("$causationId"),
+		Data:          data,
+	})
+	return body, metadata, err
+}
+
+// isJSONContentType reports whether the content type is application/json or a +json media type.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// SignWebhook returns the signature of a request, the hex HMAC-SHA256 of <timestamp>.<body> prefixed with sha256=.
+func SignWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks the signature of a request received from a WebhookDispatcher. Requests older than the
+// tolerance are rejected to prevent replays, unless the tolerance is zero.
+func VerifyWebhookSignature(secret []byte, header http.Header, body []byte, tolerance time.Duration) error {
+	timestamp := header.Get(HeaderWebhookTimestamp)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp: %q", ErrWebhookSignatureInvalid, timestamp)
+	}
+	if tolerance > 0 && time.Since(time.Unix(seconds, 0)).Abs() > tolerance {
+		return fmt.Errorf("%w: timestamp outside the tolerance: %q", ErrWebhookSignatureInvalid, timestamp)
+	}
+	if !hmac.Equal([]byte(header.Get(HeaderWebhookSignature)), []byte(SignWebhook(secret, timestamp, body))) {
+		return ErrWebhookSignatureInvalid
+	}
+	return nil
+}
+
+// MemoryDeliveryLog is a DeliveryLog kept in memory, for tests and for consumers that can afford to lose the parked
+// deliveries on restart.
+type MemoryDeliveryLog struct {
+	mu          sync.Mutex
+	attempts    []DeliveryAttempt
+	parked      []ParkedDelivery
+	checkpoints map[string]EventPosition
+}
+
+func NewMemoryDeliveryLog() *MemoryDeliveryLog {
+	return &MemoryDeliveryLog{checkpoints: map[string]EventPosition{}}
+}
+
+func (l *MemoryDeliveryLog) RecordAttempt(_ context.Context, attempt DeliveryAttempt) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.attempts = append(l.attempts, attempt)
+	return nil
+}
+
+// Attempts returns the attempts of a delivery, in order.
+func (l *MemoryDeliveryLog) Attempts(deliveryId string) []DeliveryAttempt {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var attempts []DeliveryAttempt
+	for _, attempt := range l.attempts {
+		if attempt.DeliveryId == deliveryId {
+			attempts = append(attempts, attempt)
+		}
+	}
+	return attempts
+}
+
+func (l *MemoryDeliveryLog) Park(_ context.Context, delivery ParkedDelivery) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.parked = append(l.parked, delivery)
+	return nil
+}
+
+func (l *MemoryDeliveryLog) Unpark(_ context.Context, deliveryId string) (*ParkedDelivery, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, delivery := range l.parked {
+		if delivery.DeliveryId == deliveryId {
+			l.parked = slices.Delete(l.parked, i, i+1)
+			return &delivery, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrWebhookDeliveryNotParked, deliveryId)
+}
+
+func (l *MemoryDeliveryLog) Parked(_ context.Context) ([]ParkedDelivery, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.parked), nil
+}
+
+func (l *MemoryDeliveryLog) Checkpoint(_ context.Context, subscription string) (*EventPosition, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	position, ok := l.checkpoints[subscription]
+	if !ok {
+		return nil, nil
+	}
+	return &position, nil
+}
+
+func (l *MemoryDeliveryLog) SaveCheckpoint(_ context.Context, subscription string, position EventPosition) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.checkpoints[subscription] = position
+	return nil
+}
@@ -0,0 +1,251 @@
+package golang_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/straw-hat-team/onepiece/go/onepiece/eventsourcing/onepiecemessage"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	golang "unstable"
+)
+
+// webhookReceiver records the envelopes of the requests with a valid signature.
+type webhookReceiver struct {
+	mu        sync.Mutex
+	secret    []byte
+	envelopes []golang.WebhookEnvelope
+	failing   atomic.Bool
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := golang.VerifyWebhookSignature(r.secret, req.Header, body, time.Minute); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.failing.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var envelope golang.WebhookEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.mu.Lock()
+	r.envelopes = append(r.envelopes, envelope)
+	r.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (r *webhookReceiver) received() []golang.WebhookEnvelope {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]golang.WebhookEnvelope(nil), r.envelopes...)
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	created := onepiecemessage.MessageType("com.hmbradley.deposit.plan.PlanCreated")
+	source := &sliceSource{events: []*golang.RecordedEvent{
+		recordedEvent("e1", "plan-1", created.String(), 10),
+		recordedEvent("e2", "$stats-1", "$statsCollected", 20),
+		recordedEvent("e3", "plan-1", "com.hmbradley.deposit.plan.PlanArchived", 30),
+	}}
+	source.events[0].ContentType = "application/json"
+	source.events[0].Data = []byte(`{"planId":"1"}`)
+	// NOTE: binary data is sent as base64 even when it happens to be valid JSON.
+	source.events[2].Data = []byte(`123`)
+
+	partner := &webhookReceiver{secret: []byte("partner-secret")}
+	partnerServer := httptest.NewServer(partner)
+	t.Cleanup(partnerServer.Close)
+
+	audit := &webhookReceiver{secret: []byte("audit-secret")}
+	audit.failing.Store(true)
+	auditServer := httptest.NewServer(audit)
+	t.Cleanup(auditServer.Close)
+
+	deliveries := golang.NewMemoryDeliveryLog()
+	dispatcher, err := golang.NewWebhookDispatcher(source, golang.WebhookConfig{
+		Subscriptions: []golang.WebhookSubscription{
+			{Name: "partner", URL: partnerServer.URL, Secret: partner.secret, MessageTypes: []onepiecemessage.MessageType{created}},
+			{Name: "audit", URL: auditServer.URL, Secret: audit.secret},
+		},
+		Deliveries:  deliveries,
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- dispatcher.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		return checkpointAt(deliveries, "partner", 30) && checkpointAt(deliveries, "audit", 30)
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	t.Run("delivers the filtered message types", func(t *testing.T) {
+		envelopes := partner.received()
+		require.Len(t, envelopes, 1)
+		require.Equal(t, "e1", envelopes[0].Id)
+		require.Equal(t, "plan-1", envelopes[0].StreamId)
+		require.Equal(t, "correlation-e1", envelopes[0].CorrelationId)
+		require.JSONEq(t, `{"planId":"1"}`, string(envelopes[0].Data))
+	})
+
+	t.Run("records every attempt and parks failing deliveries", func(t *testing.T) {
+		require.Len(t, deliveries.Attempts(golang.WebhookDeliveryId("partner", "e1")), 1)
+
+		attempts := deliveries.Attempts(golang.WebhookDeliveryId("audit", "e3"))
+		require.Len(t, attempts, 3)
+		for i, attempt := range attempts {
+			require.Equal(t, i+1, attempt.Attempt)
+			require.Equal(t, http.StatusServiceUnavailable, attempt.StatusCode)
+		}
+
+		parked, err := deliveries.Parked(context.Background())
+		require.NoError(t, err)
+		require.Len(t, parked, 2)
+		require.Equal(t, golang.WebhookDeliveryId("audit", "e1"), parked[0].DeliveryId)
+		require.Equal(t, golang.WebhookDeliveryId("audit", "e3"), parked[1].DeliveryId)
+	})
+
+	t.Run("replays parked deliveries", func(t *testing.T) {
+		deliveryId := golang.WebhookDeliveryId("audit", "e3")
+		require.ErrorIs(t, dispatcher.Replay(context.Background(), deliveryId), golang.ErrWebhookDeliveryFailed)
+
+		audit.failing.Store(false)
+		require.NoError(t, dispatcher.Replay(context.Background(), deliveryId))
+		require.ErrorIs(t, dispatcher.Replay(context.Background(), deliveryId), golang.ErrWebhookDeliveryNotParked)
+
+		envelopes := audit.received()
+		require.Len(t, envelopes, 1)
+		require.Equal(t, "e3", envelopes[0].Id)
+		require.JSONEq(t, `"MTIz"`, string(envelopes[0].Data))
+
+		parked, err := deliveries.Parked(context.Background())
+		require.NoError(t, err)
+		require.Len(t, parked, 1)
+	})
+
+	t.Run("resumes after the checkpoint", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- dispatcher.Run(ctx) }()
+		require.Eventually(t, func() bool {
+			source.mu.Lock()
+			defer source.mu.Unlock()
+			return len(source.froms) == 4
+		}, 5*time.Second, 10*time.Millisecond)
+		cancel()
+		require.NoError(t, <-done)
+
+		require.Equal(t, &golang.EventPosition{Commit: 30, Prepare: 30}, source.froms[2])
+		require.Equal(t, &golang.EventPosition{Commit: 30, Prepare: 30}, source.froms[3])
+		require.Len(t, partner.received(), 1)
+	})
+
+	t.Run("does not hold back the other subscriptions", func(t *testing.T) {
+		partner := &webhookReceiver{secret: []byte("partner-secret")}
+		partnerServer := httptest.NewServer(partner)
+		t.Cleanup(partnerServer.Close)
+		audit.failing.Store(true)
+
+		deliveries := golang.NewMemoryDeliveryLog()
+		dispatcher, err := golang.NewWebhookDispatcher(source, golang.WebhookConfig{
+			Subscriptions: []golang.WebhookSubscription{
+				{Name: "partner", URL: partnerServer.URL, Secret: partner.secret},
+				{Name: "audit", URL: auditServer.URL, Secret: audit.secret},
+			},
+			Deliveries: deliveries,
+			MinBackoff: time.Hour,
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- dispatcher.Run(ctx) }()
+		require.Eventually(t, func() bool {
+			return checkpointAt(deliveries, "partner", 30)
+		}, 5*time.Second, 10*time.Millisecond)
+		cancel()
+		require.NoError(t, <-done)
+
+		require.Len(t, partner.received(), 2)
+		checkpoint, err := deliveries.Checkpoint(context.Background(), "audit")
+		require.NoError(t, err)
+		require.Nil(t, checkpoint)
+	})
+}
+
+func TestWebhookDispatcherMalformedMetadata(t *testing.T) {
+	event := recordedEvent("e1", "plan-1", "com.hmbradley.deposit.plan.PlanCreated", 10)
+	event.Metadata = []byte(`{"$correlationId":`)
+	source := &sliceSource{events: []*golang.RecordedEvent{event}}
+
+	partner := &webhookReceiver{secret: []byte("partner-secret")}
+	partnerServer := httptest.NewServer(partner)
+	t.Cleanup(partnerServer.Close)
+
+	deliveries := golang.NewMemoryDeliveryLog()
+	dispatcher, err := golang.NewWebhookDispatcher(source, golang.WebhookConfig{
+		Subscriptions: []golang.WebhookSubscription{
+			{Name: "partner", URL: partnerServer.URL, Secret: partner.secret},
+		},
+		Deliveries: deliveries,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- dispatcher.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		return checkpointAt(deliveries, "partner", 10)
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	require.Empty(t, partner.received())
+	parked, err := deliveries.Parked(context.Background())
+	require.NoError(t, err)
+	require.Len(t, parked, 1)
+	require.Equal(t, golang.WebhookDeliveryId("partner", "e1"), parked[0].DeliveryId)
+	require.Contains(t, parked[0].LastError, "malformed event metadata")
+}
+
+func checkpointAt(deliveries *golang.MemoryDeliveryLog, subscription string, commit uint64) bool {
+	checkpoint, err := deliveries.Checkpoint(context.Background(), subscription)
+	return err == nil && checkpoint != nil && checkpoint.Commit == commit
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	secret := []byte("secret")
+	body := []byte(`{"id":"e1"}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	header := http.Header{}
+	header.Set(golang.HeaderWebhookTimestamp, timestamp)
+	header.Set(golang.HeaderWebhookSignature, golang.SignWebhook(secret, timestamp, body))
+
+	require.NoError(t, golang.VerifyWebhookSignature(secret, header, body, time.Minute))
+	require.ErrorIs(t, golang.VerifyWebhookSignature([]byte("other"), header, body, time.Minute), golang.ErrWebhookSignatureInvalid)
+	require.ErrorIs(t, golang.VerifyWebhookSignature(secret, header, []byte(`{}`), time.Minute), golang.ErrWebhookSignatureInvalid)
+
+	header.Set(golang.HeaderWebhookTimestamp, "0")
+	header.Set(golang.HeaderWebhookSignature, golang.SignWebhook(secret, "0", body))
+	require.ErrorIs(t, golang.VerifyWebhookSignature(secret, header, body, time.Minute), golang.ErrWebhookSignatureInvalid)
+	require.NoError(t, golang.VerifyWebhookSignature(secret, header, body, 0))
+}